@@ -0,0 +1,220 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// BlameLine is one line of a file annotated with the commit that last changed it
+type BlameLine struct {
+	Hash   string
+	Author string
+	Time   time.Time
+	Text   string
+}
+
+// blameResult is a cached blame for one file at one revision
+type blameResult struct {
+	lines []BlameLine
+	err   error
+}
+
+// maxBlames is how many blames are kept. The working copy is keyed by
+// modification time, so every edit to a blamed file adds one.
+const maxBlames = 16
+
+// blameCache keeps recent blames by blameKey, dropping the least recently used
+// past maxBlames. It is shared by every copy of the model.
+type blameCache struct {
+	order   *list.List // Of *blameEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type blameEntry struct {
+	key    string
+	result *blameResult
+}
+
+// newBlameCache creates an empty blame cache
+func newBlameCache() *blameCache {
+	return &blameCache{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the blame stored for key, marking it recently used
+func (c *blameCache) get(key string) (*blameResult, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*blameEntry).result, true
+}
+
+// put stores a blame, dropping the least recently used beyond maxBlames
+func (c *blameCache) put(key string, result *blameResult) {
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&blameEntry{key: key, result: result})
+	for c.order.Len() > maxBlames {
+		entry := c.order.Remove(c.order.Back()).(*blameEntry)
+		delete(c.entries, entry.key)
+	}
+}
+
+// blameMsg delivers a blame computed in the background
+type blameMsg struct {
+	key    string
+	result *blameResult
+}
+
+// blameKey identifies a blame in the cache; an empty revision means the working
+// copy, which is keyed by modification time so edits invalidate it
+func blameKey(path, revision string) string {
+	if revision == "" {
+		if info, err := os.Stat(path); err == nil {
+			return path + "@" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+		}
+	}
+	return path + "@" + revision
+}
+
+// loadBlameCmd runs git blame off the UI goroutine, on the file at gitPath
+// when a revision is given
+func loadBlameCmd(path, revision, gitPath string) tea.Cmd {
+	key := blameKey(path, revision)
+	return func() tea.Msg {
+		dir, name := filepath.Dir(path), filepath.Base(path)
+		args := []string{"blame", "--porcelain"}
+		if revision != "" {
+			args = append(args, revision)
+		}
+		if revision != "" && gitPath != "" {
+			// Blame takes a file name rather than a pathspec, so name it from the top
+			if top, err := runGit(dir, "rev-parse", "--show-toplevel"); err == nil {
+				dir, name = strings.TrimSpace(string(top)), gitPath
+			}
+		}
+		args = append(args, "--", name)
+
+		out, err := runGit(dir, args...)
+		result := &blameResult{err: err}
+		if err == nil {
+			result.lines = parseBlame(string(out))
+		}
+		return blameMsg{key: key, result: result}
+	}
+}
+
+// parseBlame parses `git blame --porcelain`, where commit details are only
+// given the first time each commit appears
+func parseBlame(out string) []BlameLine {
+	type commitInfo struct {
+		author string
+		time   time.Time
+	}
+	commits := make(map[string]*commitInfo)
+
+	var lines []BlameLine
+	var current *commitInfo
+	var currentHash string
+
+	for _, text := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(text, " ")
+		if current == nil && !isObjectID(key) {
+			continue // Nothing to attribute lines or details to before a header
+		}
+
+		if strings.HasPrefix(text, "\t") {
+			lines = append(lines, BlameLine{
+				Hash:   currentHash,
+				Author: current.author,
+				Time:   current.time,
+				Text:   text[1:],
+			})
+			continue
+		}
+
+		switch key {
+		case "author":
+			current.author = value
+		case "author-time":
+			seconds, _ := strconv.ParseInt(value, 10, 64)
+			current.time = time.Unix(seconds, 0)
+		default:
+			// A header line starts with the commit's object ID
+			if isObjectID(key) {
+				currentHash = key
+				if commits[key] == nil {
+					commits[key] = &commitInfo{}
+				}
+				current = commits[key]
+			}
+		}
+	}
+
+	return lines
+}
+
+// isObjectID reports whether s is a full commit hash, SHA-1 or SHA-256
+func isObjectID(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderBlame lays out each line with its short hash, author and age in place of line numbers
+func renderBlame(lines []BlameLine, width int, now time.Time, theme Theme) string {
+	const authorWidth = 12
+	hashStyle := lipgloss.NewStyle().Foreground(theme.BlameHash)
+	authorStyle := lipgloss.NewStyle().Foreground(theme.BlameAuthor)
+	ageStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	rendered := make([]string, len(lines))
+	for i, line := range lines {
+		hash := line.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		author := ansi.Truncate(line.Author, authorWidth, "…")
+		author += strings.Repeat(" ", authorWidth-lipgloss.Width(author))
+
+		gutter := hashStyle.Render(hash) + " " +
+			authorStyle.Render(author) + " " +
+			ageStyle.Render(fmt.Sprintf("%4s", formatAge(now.Sub(line.Time)))) + " │ "
+		textWidth := max(0, width-lipgloss.Width(gutter))
+		rendered[i] = gutter + ansi.Truncate(expandTabs(line.Text), textWidth, "…")
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// formatAge renders a duration compactly, e.g. "5m", "3d", "2y"
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case age < 30*24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo", int(age.Hours()/24/30))
+	}
+	return fmt.Sprintf("%dy", int(age.Hours()/24/365))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBlame(t *testing.T) {
+	sha1 := strings.Repeat("a", 40)
+	sha256 := strings.Repeat("b", 64)
+	out := strings.Join([]string{
+		sha1 + " 1 1 1",
+		"author Alice",
+		"author-mail <alice@example.com>",
+		"author-time 1700000000",
+		"author-tz +0000",
+		"summary First",
+		"filename main.go",
+		"\tpackage main",
+		sha256 + " 2 2 1",
+		"author Bob",
+		"author-time 1700003600",
+		"summary Second",
+		"filename main.go",
+		"\tauthor is not a header here",
+		// Details are only given the first time a commit appears
+		sha1 + " 3 3 1",
+		"filename main.go",
+		"\tfunc main() {}",
+		"",
+	}, "\n")
+
+	want := []BlameLine{
+		{sha1, "Alice", time.Unix(1700000000, 0), "package main"},
+		{sha256, "Bob", time.Unix(1700003600, 0), "author is not a header here"},
+		{sha1, "Alice", time.Unix(1700000000, 0), "func main() {}"},
+	}
+	got := parseBlame(out)
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestParseBlameWithoutHeader(t *testing.T) {
+	// Details before any header, as from a hash format it does not know, are skipped
+	out := "author Alice\nauthor-time 1700000000\n\tpackage main\n"
+	if got := parseBlame(out); len(got) != 0 {
+		t.Errorf("got %+v, want no lines", got)
+	}
+}
+
+func TestBlameCacheDropsLeastRecentlyUsed(t *testing.T) {
+	c := newBlameCache()
+	for i := range maxBlames {
+		c.put(fmt.Sprint(i), &blameResult{})
+	}
+	c.get("0")
+	c.put("new", &blameResult{})
+	if _, ok := c.get("1"); ok {
+		t.Error("the least recently used blame was kept")
+	}
+	for _, key := range []string{"0", "new"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("blame %s was dropped", key)
+		}
+	}
+	if len(c.entries) != maxBlames {
+		t.Errorf("%d blames kept, want %d", len(c.entries), maxBlames)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyText copies text to the system clipboard where there is one, and
+// otherwise to the terminal's
+func (m Model) copyText(text string) tea.Cmd {
+	if !m.systemClipboard {
+		return copyToClipboard(m.clipboard, m.clipboardTmux, text)
+	}
+	osc52 := copyToClipboard(m.clipboard, m.clipboardTmux, text)
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err != nil {
+			logger.Debug("no system clipboard, using the terminal's", "err", err)
+			return osc52()
+		}
+		return nil
+	}
+}
+
+// copyToClipboard sets the terminal's clipboard with an OSC 52 sequence, which
+// also works over SSH since the client's terminal does the copying. Inside tmux
+// the sequence is wrapped for tmux to pass on.
+func copyToClipboard(w io.Writer, tmux bool, text string) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		if tmux {
+			seq = seq.Tmux()
+		}
+		if _, err := seq.WriteTo(w); err != nil {
+			logger.Warn("copying to clipboard failed", "err", err)
+		}
+		return nil
+	}
+}
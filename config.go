@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// Config holds the user's settings, read from the config file and overridden by flags
+type Config struct {
+	StartDir       string `toml:"start_dir"`       // Directory to start in, the working directory when empty
+	Open           string `toml:"open"`            // File to open at startup
+	LineNumbers    bool   `toml:"line_numbers"`    // Line numbers for newly opened tabs
+	Wrap           string `toml:"wrap"`            // word, char or none
+	Theme          string `toml:"theme"`           // auto, dark, light, high-contrast or a theme from [themes]
+	ShowHidden     bool   `toml:"show_hidden"`     // List dotfiles in the navigator
+	Sort           string `toml:"sort"`            // name, modified, size or extension
+	Layout         string `toml:"layout"`          // two-pane or miller
+	NavigatorWidth int    `toml:"navigator_width"` // Widest default navigator, in columns
+	MarkdownWidth  int    `toml:"markdown_width"`  // Widest rendered markdown, 0 to fit the pane
+	RenderCacheMB  int    `toml:"render_cache_mb"` // Memory for rendered files, in megabytes, 0 to not keep them
+	RestoreSession bool   `toml:"restore_session"` // Save the session on quitting and restore it in the same directory
+	Session        string `toml:"session"`         // Named session to save and restore wherever started, implies restore_session
+
+	// Keymap is the preset the key bindings start from: default, vim, emacs or less
+	Keymap string `toml:"keymap"`
+	// Keys overrides individual bindings by name, e.g. blame = ["B"]
+	Keys map[string][]string `toml:"keys"`
+	// Themes defines custom themes by name, each starting from a built-in base
+	Themes map[string]Theme `toml:"themes,omitempty"`
+}
+
+// defaultConfig returns the settings used when neither the config file nor a flag sets them
+func defaultConfig() Config {
+	return Config{
+		LineNumbers:    true,
+		Wrap:           "word",
+		Theme:          "auto",
+		Sort:           "name",
+		Layout:         "two-pane",
+		NavigatorWidth: MaxLeftPaneWidth,
+		RenderCacheMB:  DefaultRenderCacheMB,
+		Keymap:         "default",
+	}
+}
+
+// defaultConfigPath returns the config file under $XDG_CONFIG_HOME, falling
+// back to ~/.config, or "" when neither can be determined
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "bubbletest", "config.toml")
+}
+
+// loadConfig reads the config file at path over the defaults; a missing file gives the defaults
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	meta, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("reading %s: unknown setting %q", path, undecoded[0].String())
+	}
+	return cfg, nil
+}
+
+// registerConfigFlags adds a flag for each setting, parsed into overrides
+func registerConfigFlags(fs *flag.FlagSet, overrides *Config) {
+	fs.StringVar(&overrides.StartDir, "dir", "", "directory to start in")
+	fs.StringVar(&overrides.Open, "open", "", "file to open at startup")
+	fs.BoolVar(&overrides.LineNumbers, "line-numbers", true, "show line numbers")
+	fs.StringVar(&overrides.Wrap, "wrap", "word", "line wrapping: word, char or none")
+	fs.StringVar(&overrides.Theme, "theme", "auto", "colour theme: auto, dark, light, high-contrast or a custom theme")
+	fs.BoolVar(&overrides.ShowHidden, "hidden", false, "show hidden files")
+	fs.StringVar(&overrides.Sort, "sort", "name", "navigator order: name, modified, size or extension")
+	fs.StringVar(&overrides.Layout, "layout", "two-pane", "pane arrangement: two-pane or miller")
+	fs.IntVar(&overrides.NavigatorWidth, "navigator-width", MaxLeftPaneWidth, "widest default navigator, in columns")
+	fs.IntVar(&overrides.MarkdownWidth, "markdown-width", 0, "widest rendered markdown, 0 to fit the pane")
+	fs.IntVar(&overrides.RenderCacheMB, "render-cache-mb", DefaultRenderCacheMB, "memory for rendered files, in megabytes, 0 to not keep them")
+	fs.StringVar(&overrides.Keymap, "keymap", "default", "key binding preset: default, vim, emacs or less")
+	fs.BoolVar(&overrides.RestoreSession, "restore-session", false, "save the session on quitting and restore it when started in the same directory")
+	fs.StringVar(&overrides.Session, "session", "", "save and restore a named session instead of one per directory")
+}
+
+// applyFlags copies into cfg the settings whose flags were given on the command line
+func applyFlags(fs *flag.FlagSet, cfg *Config, overrides Config) {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dir":
+			cfg.StartDir = overrides.StartDir
+		case "open":
+			cfg.Open = overrides.Open
+		case "line-numbers":
+			cfg.LineNumbers = overrides.LineNumbers
+		case "wrap":
+			cfg.Wrap = overrides.Wrap
+		case "theme":
+			cfg.Theme = overrides.Theme
+		case "hidden":
+			cfg.ShowHidden = overrides.ShowHidden
+		case "sort":
+			cfg.Sort = overrides.Sort
+		case "layout":
+			cfg.Layout = overrides.Layout
+		case "navigator-width":
+			cfg.NavigatorWidth = overrides.NavigatorWidth
+		case "markdown-width":
+			cfg.MarkdownWidth = overrides.MarkdownWidth
+		case "render-cache-mb":
+			cfg.RenderCacheMB = overrides.RenderCacheMB
+		case "keymap":
+			cfg.Keymap = overrides.Keymap
+		case "restore-session":
+			cfg.RestoreSession = overrides.RestoreSession
+		case "session":
+			cfg.Session = overrides.Session
+		}
+	})
+}
+
+// settings is a validated Config, ready to apply to a model
+type settings struct {
+	cfg        Config
+	wrapMode   WrapMode
+	listing    filebrowser.ListOptions
+	layoutMode LayoutMode
+	keys       KeyMap
+}
+
+// resolve validates the config and parses its named values
+func (c Config) resolve() (settings, error) {
+	s := settings{cfg: c}
+
+	var ok bool
+	if s.wrapMode, ok = parseWrapMode(c.Wrap); !ok {
+		return s, fmt.Errorf("unknown wrap mode %q (want word, char or none)", c.Wrap)
+	}
+	if s.listing.Sort, ok = filebrowser.ParseSortOrder(c.Sort); !ok {
+		return s, fmt.Errorf("unknown sort order %q (want name, modified, size or extension)", c.Sort)
+	}
+	if s.layoutMode, ok = parseLayoutMode(c.Layout); !ok {
+		return s, fmt.Errorf("unknown layout %q (want two-pane or miller)", c.Layout)
+	}
+	for name := range c.Themes {
+		if name == "auto" {
+			return s, fmt.Errorf("theme name %q is reserved for picking dark or light", name)
+		}
+		if _, err := resolveTheme(name, c.Themes, true); err != nil {
+			return s, err
+		}
+	}
+	if _, err := resolveTheme(c.Theme, c.Themes, true); err != nil {
+		return s, err
+	}
+	if c.NavigatorWidth < MinLeftPaneWidth {
+		return s, fmt.Errorf("navigator width %d is below the minimum of %d", c.NavigatorWidth, MinLeftPaneWidth)
+	}
+	if c.RenderCacheMB < 0 {
+		return s, fmt.Errorf("render cache size %d is negative", c.RenderCacheMB)
+	}
+	if c.Session != "" && !sessionNamePattern.MatchString(c.Session) {
+		return s, fmt.Errorf("session name %q may only contain letters, digits, '.', '_' and '-'", c.Session)
+	}
+	s.listing.ShowHidden = c.ShowHidden
+
+	keys, err := NewKeyMap(c.Keymap, c.Keys)
+	if err != nil {
+		return s, err
+	}
+	s.keys = keys
+	return s, nil
+}
+
+// applyTo configures a freshly created model, choosing the automatic theme
+// for a terminal with a dark or light background
+func (s settings) applyTo(m Model, darkBackground bool) Model {
+	m.showLineNumbers = s.cfg.LineNumbers
+	m.tab().showLineNumbers = s.cfg.LineNumbers
+	m.wrapMode = s.wrapMode
+	m.listing = s.listing
+	m.layoutMode = s.layoutMode
+	m.maxNavigatorWidth = s.cfg.NavigatorWidth
+	m.markdownWidth = s.cfg.MarkdownWidth
+	m.renderCache = newRenderCache(s.cfg.RenderCacheMB << 20)
+	m.keys = s.keys
+	theme, _ := resolveTheme(s.cfg.Theme, s.cfg.Themes, darkBackground) // Validated by resolve
+	m = m.setTheme(theme)
+
+	return m.listNow()
+}
+
+// printConfig writes the effective settings, including every key binding, as a config file
+func (s settings) printConfig(w io.Writer) error {
+	cfg := s.cfg
+	cfg.Keys = make(map[string][]string)
+	for _, nb := range s.keys.bindings() {
+		cfg.Keys[nb.name] = nb.binding.Keys()
+	}
+	return toml.NewEncoder(w).Encode(cfg)
+}
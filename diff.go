@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// DiffLineKind classifies a line of a unified diff
+type DiffLineKind int
+
+const (
+	DiffContext DiffLineKind = iota
+	DiffAdded
+	DiffRemoved
+	DiffHunkHeader
+)
+
+// DiffLine is one parsed line of a unified diff
+type DiffLine struct {
+	Kind    DiffLineKind
+	OldLine int // 0 when the line does not exist on the old side
+	NewLine int // 0 when the line does not exist on the new side
+	Text    string
+}
+
+// diffView holds a parsed diff shown in the content pane
+type diffView struct {
+	title      string // Shown after the file path in the content header
+	lines      []DiffLine
+	sideBySide bool
+	hunks      []int // Rendered line offset of each hunk, filled by render
+}
+
+// diffStyles colour the parts of a rendered diff
+type diffStyles struct {
+	added, removed, hunk, gutter lipgloss.Style
+}
+
+// newDiffStyles builds the diff styles from a theme
+func newDiffStyles(t Theme) diffStyles {
+	return diffStyles{
+		added:   lipgloss.NewStyle().Foreground(t.DiffAdded),
+		removed: lipgloss.NewStyle().Foreground(t.DiffRemoved),
+		hunk:    lipgloss.NewStyle().Foreground(t.DiffHunk),
+		gutter:  lipgloss.NewStyle().Foreground(t.Muted),
+	}
+}
+
+// diffAgainstRevision diffs the working copy of path against a git revision
+func diffAgainstRevision(path, revision string) (*diffView, error) {
+	out, err := runGit(filepath.Dir(path), "diff", "--no-color", "--no-ext-diff", revision, "--", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	return &diffView{title: "vs " + revision, lines: parseUnifiedDiff(string(out))}, nil
+}
+
+// diffFiles diffs two arbitrary files, inside a repository or not
+func diffFiles(oldPath, newPath string) (*diffView, error) {
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "--no-ext-diff", "--", oldPath, newPath)
+	out, err := cmd.Output()
+
+	// --no-index exits with 1 when the files differ
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+	return &diffView{title: "vs " + filepath.Base(oldPath), lines: parseUnifiedDiff(string(out))}, nil
+}
+
+// parseUnifiedDiff turns unified diff output into lines numbered on both sides,
+// dropping the file headers that precede the first hunk
+func parseUnifiedDiff(out string) []DiffLine {
+	var lines []DiffLine
+	oldLine, newLine := 0, 0
+	inHunk := false
+
+	for _, text := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			oldLine, newLine = parseHunkHeader(text)
+			inHunk = true
+			lines = append(lines, DiffLine{Kind: DiffHunkHeader, Text: text})
+		case !inHunk:
+			continue
+		case strings.HasPrefix(text, "+"):
+			lines = append(lines, DiffLine{Kind: DiffAdded, NewLine: newLine, Text: text[1:]})
+			newLine++
+		case strings.HasPrefix(text, "-"):
+			lines = append(lines, DiffLine{Kind: DiffRemoved, OldLine: oldLine, Text: text[1:]})
+			oldLine++
+		case strings.HasPrefix(text, " "):
+			lines = append(lines, DiffLine{Kind: DiffContext, OldLine: oldLine, NewLine: newLine, Text: text[1:]})
+			oldLine++
+			newLine++
+		case strings.HasPrefix(text, "diff "):
+			inHunk = false // Next file in a multi-file diff
+		}
+	}
+
+	return lines
+}
+
+// parseHunkHeader reads the starting line numbers from "@@ -a,b +c,d @@"
+func parseHunkHeader(header string) (oldStart, newStart int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	parse := func(field string) int {
+		start, _, _ := strings.Cut(field[1:], ",")
+		n, _ := strconv.Atoi(start)
+		return n
+	}
+	return parse(fields[1]), parse(fields[2])
+}
+
+// render draws the diff at the given width in the theme's colours and records where each hunk starts
+func (d *diffView) render(width int, theme Theme) string {
+	if len(d.lines) == 0 {
+		d.hunks = nil
+		return "No differences"
+	}
+	if d.sideBySide {
+		return d.renderSideBySide(width, newDiffStyles(theme))
+	}
+	return d.renderUnified(width, newDiffStyles(theme))
+}
+
+// lineNumberWidth returns the gutter width needed for the largest line number
+func (d *diffView) lineNumberWidth() int {
+	maxLine := 0
+	for _, line := range d.lines {
+		maxLine = max(maxLine, max(line.OldLine, line.NewLine))
+	}
+	return len(strconv.Itoa(maxLine))
+}
+
+// renderUnified renders the classic +/- view with old and new line numbers
+func (d *diffView) renderUnified(width int, styles diffStyles) string {
+	numWidth := d.lineNumberWidth()
+	number := func(n int) string {
+		if n == 0 {
+			return strings.Repeat(" ", numWidth)
+		}
+		return fmt.Sprintf("%*d", numWidth, n)
+	}
+
+	d.hunks = nil
+	rendered := make([]string, 0, len(d.lines))
+	for _, line := range d.lines {
+		if line.Kind == DiffHunkHeader {
+			d.hunks = append(d.hunks, len(rendered))
+			rendered = append(rendered, styles.hunk.Render(ansi.Truncate(line.Text, width, "…")))
+			continue
+		}
+
+		gutter := styles.gutter.Render(number(line.OldLine) + " " + number(line.NewLine) + " │")
+		textWidth := max(0, width-lipgloss.Width(gutter)-1)
+		text := ansi.Truncate(expandTabs(line.Text), textWidth, "…")
+
+		switch line.Kind {
+		case DiffAdded:
+			rendered = append(rendered, gutter+styles.added.Render("+"+text))
+		case DiffRemoved:
+			rendered = append(rendered, gutter+styles.removed.Render("-"+text))
+		default:
+			rendered = append(rendered, gutter+" "+text)
+		}
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// renderSideBySide splits the width evenly between the old and new file, pairing
+// removed lines with the added lines that replaced them
+func (d *diffView) renderSideBySide(width int, styles diffStyles) string {
+	numWidth := d.lineNumberWidth()
+	sideWidth := max(0, (width-1)/2) // One column for the divider
+	textWidth := max(0, sideWidth-numWidth-1)
+
+	side := func(n int, text string, style *lipgloss.Style) string {
+		if n == 0 {
+			return strings.Repeat(" ", sideWidth)
+		}
+		cell := ansi.Truncate(expandTabs(text), textWidth, "…")
+		cell += strings.Repeat(" ", max(0, textWidth-lipgloss.Width(cell)))
+		if style != nil {
+			cell = style.Render(cell)
+		}
+		return styles.gutter.Render(fmt.Sprintf("%*d ", numWidth, n)) + cell
+	}
+	divider := styles.gutter.Render("│")
+
+	d.hunks = nil
+	var rendered []string
+	var removed, added []DiffLine
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			left, right := strings.Repeat(" ", sideWidth), strings.Repeat(" ", sideWidth)
+			if i < len(removed) {
+				left = side(removed[i].OldLine, removed[i].Text, &styles.removed)
+			}
+			if i < len(added) {
+				right = side(added[i].NewLine, added[i].Text, &styles.added)
+			}
+			rendered = append(rendered, left+divider+right)
+		}
+		removed, added = nil, nil
+	}
+
+	for _, line := range d.lines {
+		switch line.Kind {
+		case DiffRemoved:
+			removed = append(removed, line)
+		case DiffAdded:
+			added = append(added, line)
+		case DiffHunkHeader:
+			flush()
+			d.hunks = append(d.hunks, len(rendered))
+			rendered = append(rendered, styles.hunk.Render(ansi.Truncate(line.Text, width, "…")))
+		default:
+			flush()
+			rendered = append(rendered, side(line.OldLine, line.Text, nil)+divider+side(line.NewLine, line.Text, nil))
+		}
+	}
+	flush()
+
+	return strings.Join(rendered, "\n")
+}
+
+// nextHunk returns the offset of the first hunk after offset, or -1
+func (d *diffView) nextHunk(offset int) int {
+	for _, hunk := range d.hunks {
+		if hunk > offset {
+			return hunk
+		}
+	}
+	return -1
+}
+
+// previousHunk returns the offset of the last hunk before offset, or -1
+func (d *diffView) previousHunk(offset int) int {
+	for i := len(d.hunks) - 1; i >= 0; i-- {
+		if d.hunks[i] < offset {
+			return d.hunks[i]
+		}
+	}
+	return -1
+}
+
+// expandTabs replaces tabs so truncation and padding measure the text correctly
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []DiffLine
+	}{
+		{
+			name: "several hunks",
+			out: "diff --git a/f.txt b/f.txt\nindex 1234567..89abcde 100644\n--- a/f.txt\n+++ b/f.txt\n" +
+				"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+				"@@ -10,2 +10,3 @@ func x() {\n j\n+k\n l\n",
+			want: []DiffLine{
+				{Kind: DiffHunkHeader, Text: "@@ -1,3 +1,3 @@"},
+				{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "a"},
+				{Kind: DiffRemoved, OldLine: 2, Text: "b"},
+				{Kind: DiffAdded, NewLine: 2, Text: "B"},
+				{Kind: DiffContext, OldLine: 3, NewLine: 3, Text: "c"},
+				{Kind: DiffHunkHeader, Text: "@@ -10,2 +10,3 @@ func x() {"},
+				{Kind: DiffContext, OldLine: 10, NewLine: 10, Text: "j"},
+				{Kind: DiffAdded, NewLine: 11, Text: "k"},
+				{Kind: DiffContext, OldLine: 11, NewLine: 12, Text: "l"},
+			},
+		},
+		{
+			name: "no newline at end of file, without counts",
+			out:  "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n",
+			want: []DiffLine{
+				{Kind: DiffHunkHeader, Text: "@@ -1 +1 @@"},
+				{Kind: DiffRemoved, OldLine: 1, Text: "old"},
+				{Kind: DiffAdded, NewLine: 1, Text: "new"},
+			},
+		},
+		{
+			name: "headers of the next file",
+			out:  "@@ -1 +1 @@\n-x\n+y\ndiff --git a/g b/g\n--- a/g\n+++ b/g\n@@ -4,0 +5 @@\n+--- a line that looks like a header\n",
+			want: []DiffLine{
+				{Kind: DiffHunkHeader, Text: "@@ -1 +1 @@"},
+				{Kind: DiffRemoved, OldLine: 1, Text: "x"},
+				{Kind: DiffAdded, NewLine: 1, Text: "y"},
+				{Kind: DiffHunkHeader, Text: "@@ -4,0 +5 @@"},
+				{Kind: DiffAdded, NewLine: 5, Text: "--- a line that looks like a header"},
+			},
+		},
+		{"no differences", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUnifiedDiff(tt.out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		header             string
+		oldStart, newStart int
+	}{
+		{"@@ -1,3 +1,4 @@", 1, 1},
+		{"@@ -1 +1 @@", 1, 1},
+		{"@@ -0,0 +1,2 @@", 0, 1},
+		{"@@ -12,5 +15 @@ func main() {", 12, 15},
+		{"@@", 0, 0},
+	}
+	for _, tt := range tests {
+		if oldStart, newStart := parseHunkHeader(tt.header); oldStart != tt.oldStart || newStart != tt.newStart {
+			t.Errorf("parseHunkHeader(%q) = %d, %d, want %d, %d", tt.header, oldStart, newStart, tt.oldStart, tt.newStart)
+		}
+	}
+}
+
+func TestSideBySidePairsReplacedLines(t *testing.T) {
+	d := &diffView{sideBySide: true, lines: parseUnifiedDiff("@@ -1,4 +1,3 @@\n a\n-old1\n-old2\n+new1\n z\n")}
+	rows := strings.Split(ansi.Strip(d.render(41, DarkTheme)), "\n")
+	want := []struct{ left, right string }{
+		{"", ""}, // The hunk header
+		{"1 a", "1 a"},
+		{"2 old1", "2 new1"},
+		{"3 old2", ""}, // Nothing left to pair it with
+		{"4 z", "3 z"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d:\n%s", len(rows), len(want), strings.Join(rows, "\n"))
+	}
+	for i, w := range want[1:] {
+		left, right, ok := strings.Cut(rows[i+1], "│")
+		if !ok || strings.TrimSpace(left) != w.left || strings.TrimSpace(right) != w.right {
+			t.Errorf("row %d = %q, want %q | %q", i+1, rows[i+1], w.left, w.right)
+		}
+	}
+	if !reflect.DeepEqual(d.hunks, []int{0}) {
+		t.Errorf("hunks at %v, want [0]", d.hunks)
+	}
+}
@@ -0,0 +1,400 @@
+// Package filebrowser is a file navigator with a preview of the selected entry,
+// for embedding in Bubble Tea programs. The bubbletest viewer has a navigator of
+// its own and shares only the listing helpers, such as ReadDir and WithinRoot.
+//
+//	browser := filebrowser.New(dir, filebrowser.WithRoot(dir))
+//
+// Forward messages to the browser's Update and handle the FileSelectedMsg and
+// DirChangedMsg it emits.
+package filebrowser
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// MaxPreviewSize is how much of a file is read for its preview
+const MaxPreviewSize = 1 << 20
+
+// borderSize is the columns and rows a rounded border takes around a pane
+const borderSize = 2
+
+// FileSelectedMsg is emitted when a file is opened from the navigator
+type FileSelectedMsg struct {
+	Path string
+}
+
+// DirChangedMsg is emitted when the navigator moves to another directory
+type DirChangedMsg struct {
+	Dir string
+}
+
+// item is an Entry shown in the navigator's list
+type item struct {
+	Entry
+}
+
+func (i item) FilterValue() string { return i.Name }
+func (i item) Title() string       { return i.Name }
+func (i item) Description() string {
+	if i.IsDir {
+		return "Directory"
+	}
+	return "File"
+}
+
+// Model is a navigator listing a directory beside a preview of the selected
+// entry. It implements tea.Model and is sized by tea.WindowSizeMsg or SetSize.
+type Model struct {
+	dir            string
+	root           string // Navigation never leaves root when it is set
+	listing        ListOptions
+	renderers      []Renderer
+	keys           KeyMap
+	styles         Styles
+	logger         *slog.Logger
+	navigatorWidth int // Widest the navigator pane gets, including its border
+
+	list           list.Model
+	preview        viewport.Model
+	previewPath    string
+	previewFocused bool
+	history        []string // Directories visited before dir, most recent last
+	width, height  int
+}
+
+// Option configures a Model created by New
+type Option func(*Model)
+
+// WithRoot confines navigation to root and shows paths relative to it
+func WithRoot(root string) Option {
+	return func(m *Model) { m.root = root }
+}
+
+// WithListOptions sets which entries are listed and in what order
+func WithListOptions(opts ListOptions) Option {
+	return func(m *Model) { m.listing = opts }
+}
+
+// WithFilter lists only the entries filter returns true for
+func WithFilter(filter func(Entry) bool) Option {
+	return func(m *Model) { m.listing.Filter = filter }
+}
+
+// WithRenderers sets the renderers tried in order for the preview of a file
+func WithRenderers(renderers ...Renderer) Option {
+	return func(m *Model) { m.renderers = renderers }
+}
+
+// WithKeyMap replaces the default key bindings
+func WithKeyMap(keys KeyMap) Option {
+	return func(m *Model) { m.keys = keys }
+}
+
+// WithStyles replaces the default styles
+func WithStyles(styles Styles) Option {
+	return func(m *Model) { m.styles = styles }
+}
+
+// WithLogger logs directory and file errors to logger instead of discarding them
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Model) { m.logger = logger }
+}
+
+// WithNavigatorWidth caps the navigator pane's width, 40 columns by default
+func WithNavigatorWidth(width int) Option {
+	return func(m *Model) { m.navigatorWidth = width }
+}
+
+// New creates a browser listing dir
+func New(dir string, opts ...Option) Model {
+	m := Model{
+		dir:            dir,
+		renderers:      DefaultRenderers(),
+		keys:           DefaultKeyMap(),
+		styles:         DefaultStyles(),
+		logger:         slog.New(slog.DiscardHandler),
+		navigatorWidth: 40,
+		preview:        viewport.New(0, 0),
+	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	delegate := list.NewDefaultDelegate()
+	selected := m.styles.SelectedItem.GetForeground()
+	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Foreground(selected).BorderForeground(selected)
+	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.Foreground(selected).BorderForeground(selected)
+	delegate.Styles.NormalDesc = delegate.Styles.NormalDesc.Inherit(m.styles.Muted)
+
+	m.list = list.New(nil, delegate, 0, 0)
+	m.list.Styles.Title = m.styles.Title
+	m.list.SetShowStatusBar(false)
+	m.list.SetFilteringEnabled(false)
+	m.list.SetShowHelp(false)
+	return m.load()
+}
+
+// Init implements tea.Model
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Dir returns the directory being listed
+func (m Model) Dir() string {
+	return m.dir
+}
+
+// SelectedEntry returns the entry under the navigator's cursor
+func (m Model) SelectedEntry() (Entry, bool) {
+	i, ok := m.list.SelectedItem().(item)
+	return i.Entry, ok
+}
+
+// KeyMap returns the key bindings, e.g. for showing them with the help component
+func (m Model) KeyMap() KeyMap {
+	return m.keys
+}
+
+// SetSize fits the browser into width columns and height rows
+func (m Model) SetSize(width, height int) Model {
+	m.width, m.height = width, height
+
+	navigatorWidth := min(m.navigatorWidth, width/3)
+	m.list.SetSize(max(0, navigatorWidth-borderSize), max(0, height-borderSize))
+	m.preview.Width = max(0, width-navigatorWidth-borderSize)
+	m.preview.Height = max(0, height-borderSize)
+	m.previewPath = "" // Re-render for the new width
+	return m.renderPreview()
+}
+
+// SetDir lists dir, emitting a DirChangedMsg
+func (m Model) SetDir(dir string) (Model, tea.Cmd) {
+	if !WithinRoot(dir, m.root) {
+		m.logger.Warn("directory outside root", "dir", dir, "root", m.root)
+		return m, nil
+	}
+	m.history = append(m.history, m.dir)
+	m.dir = dir
+	m = m.load()
+	return m, emit(DirChangedMsg{Dir: dir})
+}
+
+// Update implements tea.Model, handling key presses and window sizes
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return m.SetSize(msg.Width, msg.Height), nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// handleKey moves in the focused pane or navigates the tree
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.SwitchFocus):
+		m.previewFocused = !m.previewFocused
+		return m, nil
+	case key.Matches(msg, m.keys.Parent):
+		return m.goToParent()
+	case key.Matches(msg, m.keys.Back):
+		if len(m.history) == 0 {
+			return m, nil
+		}
+		m.dir = m.history[len(m.history)-1]
+		m.history = m.history[:len(m.history)-1]
+		m = m.load()
+		return m, emit(DirChangedMsg{Dir: m.dir})
+	case key.Matches(msg, m.keys.Open) && !m.previewFocused:
+		entry, ok := m.SelectedEntry()
+		if !ok {
+			return m, nil
+		}
+		if entry.IsDir {
+			return m.SetDir(entry.Path)
+		}
+		return m, emit(FileSelectedMsg{Path: entry.Path})
+	}
+
+	if m.previewFocused {
+		m.scrollPreview(msg)
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.list.CursorUp()
+	case key.Matches(msg, m.keys.Down):
+		m.list.CursorDown()
+	case key.Matches(msg, m.keys.PageUp):
+		m.list.PrevPage()
+	case key.Matches(msg, m.keys.PageDown):
+		m.list.NextPage()
+	case key.Matches(msg, m.keys.Top):
+		m.list.Select(0)
+	case key.Matches(msg, m.keys.Bottom):
+		m.list.Select(len(m.list.Items()) - 1)
+	default:
+		return m, nil
+	}
+	return m.renderPreview(), nil
+}
+
+// scrollPreview moves the preview for a movement key
+func (m *Model) scrollPreview(msg tea.KeyMsg) {
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.preview.ScrollUp(1)
+	case key.Matches(msg, m.keys.Down):
+		m.preview.ScrollDown(1)
+	case key.Matches(msg, m.keys.PageUp):
+		m.preview.PageUp()
+	case key.Matches(msg, m.keys.PageDown):
+		m.preview.PageDown()
+	case key.Matches(msg, m.keys.Top):
+		m.preview.GotoTop()
+	case key.Matches(msg, m.keys.Bottom):
+		m.preview.GotoBottom()
+	}
+}
+
+// goToParent lists the parent directory with the one it came from selected,
+// stopping at the top of the tree
+func (m Model) goToParent() (tea.Model, tea.Cmd) {
+	parent := filepath.Dir(m.dir)
+	if parent == m.dir || (m.root != "" && filepath.Clean(m.dir) == filepath.Clean(m.root)) {
+		return m, nil
+	}
+
+	previous := m.dir
+	m, cmd := m.SetDir(parent)
+	for i, listed := range m.list.Items() {
+		if listed.(item).Path == previous {
+			m.list.Select(i)
+			break
+		}
+	}
+	return m.renderPreview(), cmd
+}
+
+// load lists dir with the cursor at the top
+func (m Model) load() Model {
+	entries, err := ReadDir(m.dir, m.root, m.listing)
+	if err != nil {
+		m.logger.Error("reading directory", "dir", m.dir, "err", err)
+	}
+
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = item{entry}
+	}
+	m.list.SetItems(items)
+	m.list.Title = DisplayPath(m.dir, m.root)
+	m.list.Select(0)
+	m.previewPath = ""
+	return m.renderPreview()
+}
+
+// renderPreview shows the entry under the cursor in the preview pane
+func (m Model) renderPreview() Model {
+	entry, ok := m.SelectedEntry()
+	if !ok || entry.Name == ".." {
+		m.previewPath = ""
+		m.preview.SetContent("")
+		return m
+	}
+	if entry.Path == m.previewPath {
+		return m
+	}
+
+	m.previewPath = entry.Path
+	m.preview.SetContent(m.renderEntry(entry))
+	m.preview.GotoTop()
+	return m
+}
+
+// renderEntry renders a file with the first renderer that accepts it, or lists a directory
+func (m Model) renderEntry(entry Entry) string {
+	if entry.IsDir {
+		entries, err := ReadDir(entry.Path, m.root, m.listing)
+		if err != nil {
+			m.logger.Error("reading directory", "dir", entry.Path, "err", err)
+			return m.styles.Muted.Render("Error reading directory: " + err.Error())
+		}
+		var names []string
+		for _, e := range entries {
+			switch {
+			case e.Name == "..":
+			case e.IsDir:
+				names = append(names, e.Name+"/")
+			default:
+				names = append(names, e.Name)
+			}
+		}
+		if len(names) == 0 {
+			return m.styles.Muted.Render("Empty directory")
+		}
+		return strings.Join(names, "\n")
+	}
+
+	content, err := readPreview(entry.Path)
+	if err != nil {
+		m.logger.Error("reading file", "path", entry.Path, "err", err)
+		return m.styles.Muted.Render("Error reading file: " + err.Error())
+	}
+	for _, r := range m.renderers {
+		if rendered, ok := r.Render(entry.Path, content, m.preview.Width); ok {
+			return rendered
+		}
+	}
+	return m.styles.Muted.Render(fmt.Sprintf("No preview for %s", entry.Name))
+}
+
+// readPreview reads up to MaxPreviewSize bytes of a file
+func readPreview(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, MaxPreviewSize))
+}
+
+// View implements tea.Model
+func (m Model) View() string {
+	if m.width == 0 || m.height == 0 {
+		return ""
+	}
+
+	navigatorStyle, previewStyle := m.styles.FocusedBorder, m.styles.UnfocusedBorder
+	if m.previewFocused {
+		navigatorStyle, previewStyle = previewStyle, navigatorStyle
+	}
+
+	navigator := navigatorStyle.
+		Width(m.list.Width()).
+		Height(m.list.Height()).
+		Render(m.list.View())
+	preview := previewStyle.
+		Width(m.preview.Width).
+		Height(m.preview.Height).
+		Render(m.preview.View())
+	return lipgloss.JoinHorizontal(lipgloss.Top, navigator, preview)
+}
+
+// emit returns a command delivering msg
+func emit(msg tea.Msg) tea.Cmd {
+	return func() tea.Msg { return msg }
+}
@@ -0,0 +1,105 @@
+package filebrowser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// press sends a key to the browser, returning it with the message it emitted
+func press(t *testing.T, m Model, msg tea.KeyMsg) (Model, tea.Msg) {
+	t.Helper()
+	updated, cmd := m.Update(msg)
+	var emitted tea.Msg
+	if cmd != nil {
+		emitted = cmd()
+	}
+	return updated.(Model), emitted
+}
+
+var (
+	enter     = tea.KeyMsg{Type: tea.KeyEnter}
+	down      = tea.KeyMsg{Type: tea.KeyDown}
+	left      = tea.KeyMsg{Type: tea.KeyLeft}
+	backspace = tea.KeyMsg{Type: tea.KeyBackspace}
+)
+
+// newTestBrowser creates a browser confined to a tree of sub/b.txt and a.txt
+func newTestBrowser(t *testing.T) (Model, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := New(root, WithRoot(root))
+	return m.SetSize(80, 20), root
+}
+
+func TestOpenEmitsMessages(t *testing.T) {
+	m, root := newTestBrowser(t)
+	sub := filepath.Join(root, "sub")
+
+	m, msg := press(t, m, enter) // Directories come first
+	if msg != (DirChangedMsg{Dir: sub}) || m.Dir() != sub {
+		t.Fatalf("opening sub emitted %#v in %s", msg, m.Dir())
+	}
+
+	m, _ = press(t, m, down) // Past ".."
+	m, msg = press(t, m, enter)
+	if want := (FileSelectedMsg{Path: filepath.Join(sub, "b.txt")}); msg != want {
+		t.Errorf("opening b.txt emitted %#v, want %#v", msg, want)
+	}
+	if m.Dir() != sub {
+		t.Errorf("dir = %s after opening a file, want %s", m.Dir(), sub)
+	}
+}
+
+func TestRootConfinement(t *testing.T) {
+	m, root := newTestBrowser(t)
+
+	m, cmd := m.SetDir(filepath.Dir(root))
+	if cmd != nil || m.Dir() != root {
+		t.Errorf("SetDir outside the root moved to %s", m.Dir())
+	}
+	m, msg := press(t, m, left)
+	if msg != nil || m.Dir() != root {
+		t.Errorf("parent of the root emitted %#v in %s", msg, m.Dir())
+	}
+
+	// From below, the parent is the root with the directory left selected
+	m, _ = press(t, m, enter)
+	m, msg = press(t, m, left)
+	if msg != (DirChangedMsg{Dir: root}) || m.Dir() != root {
+		t.Fatalf("parent of sub emitted %#v in %s", msg, m.Dir())
+	}
+	if entry, ok := m.SelectedEntry(); !ok || entry.Name != "sub" {
+		t.Errorf("selected %+v after going up, want sub", entry)
+	}
+}
+
+func TestBackReturnsThroughHistory(t *testing.T) {
+	m, root := newTestBrowser(t)
+	sub := filepath.Join(root, "sub")
+
+	m, _ = press(t, m, enter) // root → sub
+	m, _ = press(t, m, left)  // sub → root
+	m, msg := press(t, m, backspace)
+	if msg != (DirChangedMsg{Dir: sub}) || m.Dir() != sub {
+		t.Fatalf("first back emitted %#v in %s, want %s", msg, m.Dir(), sub)
+	}
+	m, msg = press(t, m, backspace)
+	if msg != (DirChangedMsg{Dir: root}) || m.Dir() != root {
+		t.Fatalf("second back emitted %#v in %s, want %s", msg, m.Dir(), root)
+	}
+	if m, msg = press(t, m, backspace); msg != nil || m.Dir() != root {
+		t.Errorf("back with no history emitted %#v in %s", msg, m.Dir())
+	}
+}
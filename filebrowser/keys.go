@@ -0,0 +1,47 @@
+package filebrowser
+
+import "github.com/charmbracelet/bubbles/key"
+
+// KeyMap is the key bindings of the browser. It implements help.KeyMap so the
+// bindings can be shown with the bubbles help component.
+type KeyMap struct {
+	Up          key.Binding
+	Down        key.Binding
+	PageUp      key.Binding
+	PageDown    key.Binding
+	Top         key.Binding
+	Bottom      key.Binding
+	Open        key.Binding // Enter the selected directory or select the file
+	Parent      key.Binding // Go up one directory
+	Back        key.Binding // Return to the previously visited directory
+	SwitchFocus key.Binding // Move the keyboard between the navigator and the preview
+}
+
+// DefaultKeyMap returns arrow key bindings with vi-style alternatives
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:          key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+		Down:        key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+		PageUp:      key.NewBinding(key.WithKeys("pgup"), key.WithHelp("pgup", "page up")),
+		PageDown:    key.NewBinding(key.WithKeys("pgdown", " "), key.WithHelp("pgdn", "page down")),
+		Top:         key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("g", "top")),
+		Bottom:      key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("G", "bottom")),
+		Open:        key.NewBinding(key.WithKeys("enter", "right", "l"), key.WithHelp("enter", "open")),
+		Parent:      key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "parent")),
+		Back:        key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "back")),
+		SwitchFocus: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "switch pane")),
+	}
+}
+
+// ShortHelp returns the bindings for the compact help view
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Open, k.Parent, k.SwitchFocus}
+}
+
+// FullHelp returns every binding, grouped into columns
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.Open, k.Parent, k.Back, k.SwitchFocus},
+	}
+}
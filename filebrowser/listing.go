@@ -0,0 +1,176 @@
+package filebrowser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry is a file or directory listed by ReadDir
+type Entry struct {
+	Name  string
+	Path  string
+	IsDir bool
+}
+
+// SortOrder is the order of entries in a listing, always with directories first
+type SortOrder int
+
+const (
+	SortByName      SortOrder = iota
+	SortByModified            // Newest first
+	SortBySize                // Largest first
+	SortByExtension           // Grouped by extension, then by name
+)
+
+// ParseSortOrder maps a sort order name (name, modified, size or extension) to a SortOrder
+func ParseSortOrder(name string) (SortOrder, bool) {
+	switch name {
+	case "name", "":
+		return SortByName, true
+	case "modified":
+		return SortByModified, true
+	case "size":
+		return SortBySize, true
+	case "extension":
+		return SortByExtension, true
+	}
+	return SortByName, false
+}
+
+// ListOptions controls which entries ReadDir returns and in what order
+type ListOptions struct {
+	ShowHidden bool
+	Sort       SortOrder
+	DirsOnly   bool     // Leave out files, e.g. for picking directories
+	Extensions []string // Only list files with these extensions, with dots, when not empty
+	// Filter, when set, keeps only the entries it returns true for
+	Filter func(Entry) bool
+}
+
+// ReadDir lists dir with a ".." entry first, never offering to leave root when it is set
+func ReadDir(dir, root string, opts ListOptions) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+
+	// Add parent directory if not root
+	if dir != "/" && (root == "" || filepath.Clean(dir) != filepath.Clean(root)) {
+		entries = append(entries, Entry{Name: "..", Path: filepath.Dir(dir), IsDir: true})
+	}
+
+	// Skip hidden files unless asked for them, and files filtered out
+	visible := dirEntries[:0]
+	for _, entry := range dirEntries {
+		switch {
+		case !opts.ShowHidden && strings.HasPrefix(entry.Name(), "."):
+		case opts.Filter != nil && !opts.Filter(Entry{Name: entry.Name(), Path: filepath.Join(dir, entry.Name()), IsDir: entry.IsDir()}):
+		case entry.IsDir():
+			visible = append(visible, entry)
+		case opts.DirsOnly:
+		case len(opts.Extensions) > 0 && !HasExtension(entry.Name(), opts.Extensions):
+		default:
+			visible = append(visible, entry)
+		}
+	}
+	dirEntries = visible
+
+	// Sort entries: directories first, then files
+	infos := make(map[string]os.FileInfo, len(dirEntries))
+	if opts.Sort == SortByModified || opts.Sort == SortBySize {
+		for _, entry := range dirEntries {
+			if info, err := entry.Info(); err == nil {
+				infos[entry.Name()] = info
+			}
+		}
+	}
+	sort.SliceStable(dirEntries, func(i, j int) bool {
+		a, b := dirEntries[i], dirEntries[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		infoA, infoB := infos[a.Name()], infos[b.Name()]
+		switch {
+		case opts.Sort == SortByModified && infoA != nil && infoB != nil && !infoA.ModTime().Equal(infoB.ModTime()):
+			return infoA.ModTime().After(infoB.ModTime())
+		case opts.Sort == SortBySize && infoA != nil && infoB != nil && infoA.Size() != infoB.Size():
+			return infoA.Size() > infoB.Size()
+		case opts.Sort == SortByExtension && filepath.Ext(a.Name()) != filepath.Ext(b.Name()):
+			return filepath.Ext(a.Name()) < filepath.Ext(b.Name())
+		}
+		return a.Name() < b.Name()
+	})
+
+	for _, entry := range dirEntries {
+		entries = append(entries, Entry{
+			Name:  entry.Name(),
+			Path:  filepath.Join(dir, entry.Name()),
+			IsDir: entry.IsDir(),
+		})
+	}
+	return entries, nil
+}
+
+// HasExtension reports whether name ends in one of exts, ignoring case
+func HasExtension(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == strings.ToLower(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayPath formats a path for display, relative to root when navigation is confined
+func DisplayPath(path, root string) string {
+	if root == "" {
+		return formatDirectoryPath(path)
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// WithinRoot reports whether path (after resolving symlinks) stays inside root
+func WithinRoot(path, root string) bool {
+	if root == "" {
+		return true
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(resolvedRoot, resolved)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// formatDirectoryPath formats a directory path with ~ substitution for home directory
+func formatDirectoryPath(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	if strings.HasPrefix(path, homeDir) {
+		return strings.Replace(path, homeDir, "~", 1)
+	}
+
+	return path
+}
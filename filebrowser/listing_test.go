@@ -0,0 +1,123 @@
+package filebrowser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func names(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Name)
+	}
+	return out
+}
+
+func TestReadDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b.go", "a.md", ".hidden", "sub/x.txt"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"directories first", ListOptions{}, []string{"..", "sub", "a.md", "b.go"}},
+		{"hidden", ListOptions{ShowHidden: true}, []string{"..", "sub", ".hidden", "a.md", "b.go"}},
+		{"by extension", ListOptions{Sort: SortByExtension}, []string{"..", "sub", "b.go", "a.md"}},
+		{"dirs only", ListOptions{DirsOnly: true}, []string{"..", "sub"}},
+		{"extensions", ListOptions{Extensions: []string{".GO"}}, []string{"..", "sub", "b.go"}},
+		{"filter", ListOptions{Filter: func(e Entry) bool { return e.Name != "a.md" }}, []string{"..", "sub", "b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ReadDir(root, "", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Confined to root, the listing offers no way up from it
+	entries, err := ReadDir(root, root, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(entries); !reflect.DeepEqual(got, []string{"sub", "a.md", "b.go"}) {
+		t.Errorf("at root got %v", got)
+	}
+}
+
+func TestDisplayPath(t *testing.T) {
+	tests := []struct{ path, root, want string }{
+		{"/srv/logs", "/srv/logs", "/"},
+		{"/srv/logs/app/today.log", "/srv/logs", "/app/today.log"},
+	}
+	for _, tt := range tests {
+		if got := DisplayPath(tt.path, tt.root); got != tt.want {
+			t.Errorf("DisplayPath(%q, %q) = %q, want %q", tt.path, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestWithinRoot(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	for _, dir := range []string{"root/sub", "root2"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"root/file.txt", "root2/secret.txt"} {
+		if err := os.WriteFile(filepath.Join(base, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"root/escape":   filepath.Join(base, "root2"),
+		"root/leak.txt": filepath.Join(base, "root2", "secret.txt"),
+		"root/inside":   filepath.Join(root, "sub"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(base, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "file.txt"), true},
+		{filepath.Join(root, "sub", ".."), true},
+		{filepath.Join(root, "inside"), true}, // A symlink staying inside
+		{filepath.Join(root, ".."), false},
+		{root + "/sub/../../root2", false},
+		{filepath.Join(base, "root2"), false}, // A sibling sharing the root's name as a prefix
+		{filepath.Join(root, "escape"), false},
+		{filepath.Join(root, "escape", "secret.txt"), false},
+		{filepath.Join(root, "leak.txt"), false},
+		{filepath.Join(root, "missing.txt"), false}, // Cannot be resolved, so not trusted
+	}
+	for _, tt := range tests {
+		if got := WithinRoot(tt.path, root); got != tt.want {
+			t.Errorf("WithinRoot(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if !WithinRoot(filepath.Join(base, "root2"), "") {
+		t.Error("a path was refused without a root")
+	}
+}
@@ -0,0 +1,65 @@
+package filebrowser
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// Renderer turns a file into what the preview shows
+type Renderer interface {
+	// Render returns the preview of the file at path wrapped to width, or false
+	// to leave the file to the next renderer
+	Render(path string, content []byte, width int) (string, bool)
+}
+
+// RendererFunc adapts a function to a Renderer
+type RendererFunc func(path string, content []byte, width int) (string, bool)
+
+// Render calls f
+func (f RendererFunc) Render(path string, content []byte, width int) (string, bool) {
+	return f(path, content, width)
+}
+
+// MarkdownRenderer renders .md and .markdown files with the named glamour
+// style, e.g. "dark" or "light"
+func MarkdownRenderer(style string) Renderer {
+	return RendererFunc(func(path string, content []byte, width int) (string, bool) {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".md" && ext != ".markdown" {
+			return "", false
+		}
+		renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle(style), glamour.WithWordWrap(width))
+		if err != nil {
+			return "", false
+		}
+		rendered, err := renderer.Render(string(content))
+		if err != nil {
+			return "", false
+		}
+		return rendered, true
+	})
+}
+
+// TextRenderer shows any file as plain text wrapped at word boundaries, and
+// binary files as a note
+func TextRenderer() Renderer {
+	return RendererFunc(func(path string, content []byte, width int) (string, bool) {
+		if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+			return "Binary file", true
+		}
+		text := strings.ReplaceAll(string(content), "\t", "    ")
+		if width > 0 {
+			text = wordwrap.String(text, width)
+		}
+		return text, true
+	})
+}
+
+// DefaultRenderers returns markdown rendering in the dark style with plain text for everything else
+func DefaultRenderers() []Renderer {
+	return []Renderer{MarkdownRenderer("dark"), TextRenderer()}
+}
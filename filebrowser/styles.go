@@ -0,0 +1,27 @@
+package filebrowser
+
+import "github.com/charmbracelet/lipgloss"
+
+// Styles are the lipgloss styles the browser is drawn with
+type Styles struct {
+	FocusedBorder   lipgloss.Style // The pane with the keyboard
+	UnfocusedBorder lipgloss.Style
+	Title           lipgloss.Style // The current directory above the listing
+	SelectedItem    lipgloss.Style // The entry under the cursor
+	Muted           lipgloss.Style // Entry descriptions and messages in the preview
+}
+
+// DefaultStyles returns the styles used by the bubbletest viewer's dark theme
+func DefaultStyles() Styles {
+	border := lipgloss.NewStyle().Border(lipgloss.RoundedBorder())
+	return Styles{
+		FocusedBorder:   border.BorderForeground(lipgloss.Color("42")),  // Green
+		UnfocusedBorder: border.BorderForeground(lipgloss.Color("240")), // Gray
+		Title: lipgloss.NewStyle().
+			Background(lipgloss.Color("62")).  // Purple
+			Foreground(lipgloss.Color("230")). // Cream
+			Padding(0, 1),
+		SelectedItem: lipgloss.NewStyle().Foreground(lipgloss.Color("170")), // Pink
+		Muted:        lipgloss.NewStyle().Foreground(lipgloss.Color("240")), // Gray
+	}
+}
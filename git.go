@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// GitStatus is the state of a path in its git working tree, ordered by display priority
+type GitStatus int
+
+const (
+	GitClean GitStatus = iota
+	GitIgnored
+	GitUntracked
+	GitStaged
+	GitModified
+	GitConflicted
+)
+
+// gitStatusRefreshInterval is how often the working tree is checked for changes
+const gitStatusRefreshInterval = 2 * time.Second
+
+// Bounds on the working tree walked for changes, so checking a large tree stays cheap
+const (
+	fingerprintMaxDepth   = 4    // Directory levels below the listed one
+	fingerprintMaxEntries = 5000 // Entries statted per check
+)
+
+// Badge returns the short marker shown next to a file name
+func (s GitStatus) Badge() string {
+	switch s {
+	case GitIgnored:
+		return "!"
+	case GitUntracked:
+		return "?"
+	case GitStaged:
+		return "+"
+	case GitModified:
+		return "M"
+	case GitConflicted:
+		return "U"
+	}
+	return ""
+}
+
+// Color returns the foreground colour used for a file with this status
+func (s GitStatus) Color(t Theme) lipgloss.Color {
+	switch s {
+	case GitIgnored:
+		return t.GitIgnored
+	case GitUntracked:
+		return t.GitUntracked
+	case GitStaged:
+		return t.GitStaged
+	case GitModified:
+		return t.GitModified
+	case GitConflicted:
+		return t.GitConflicted
+	}
+	return ""
+}
+
+// GitRepoStatus is a snapshot of `git status` for a whole repository
+type GitRepoStatus struct {
+	Root   string // Working tree root
+	GitDir string // Absolute path of the .git directory
+	Branch string
+	Ahead  int
+	Behind int
+
+	entries map[string]GitStatus // Slash-separated paths relative to Root, dirs end in "/"
+	dirs    map[string]GitStatus // Aggregated status of directories containing changes
+}
+
+// Contains reports whether path lies inside this repository's working tree
+func (s *GitRepoStatus) Contains(path string) bool {
+	rel, err := filepath.Rel(s.Root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// StatusOf returns the status of path, aggregating children for directories
+func (s *GitRepoStatus) StatusOf(path string, isDir bool) GitStatus {
+	rel, err := filepath.Rel(s.Root, path)
+	if err != nil || rel == "." {
+		return GitClean
+	}
+	rel = filepath.ToSlash(rel)
+
+	if status, ok := s.entries[rel]; ok {
+		return status
+	}
+
+	// Untracked and ignored directories are reported once for everything beneath them
+	for dir := rel; dir != "."; dir = pathDir(dir) {
+		if status, ok := s.entries[dir+"/"]; ok {
+			return status
+		}
+	}
+
+	if isDir {
+		return s.dirs[rel]
+	}
+	return GitClean
+}
+
+// Title returns the branch summary shown in the navigator title, e.g. "main ↑1 ↓2"
+func (s *GitRepoStatus) Title() string {
+	title := s.Branch
+	if s.Ahead > 0 {
+		title += fmt.Sprintf(" ↑%d", s.Ahead)
+	}
+	if s.Behind > 0 {
+		title += fmt.Sprintf(" ↓%d", s.Behind)
+	}
+	return title
+}
+
+// pathDir is path.Dir for the slash-separated paths git reports
+func pathDir(p string) string {
+	i := strings.LastIndex(strings.TrimSuffix(p, "/"), "/")
+	if i < 0 {
+		return "."
+	}
+	return p[:i]
+}
+
+// runGit runs a git command in dir and returns its stdout
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// loadGitStatus returns the status of the repository containing dir, or nil if there is none
+func loadGitStatus(dir string) (*GitRepoStatus, error) {
+	out, err := runGit(dir, "rev-parse", "--show-toplevel", "--absolute-git-dir")
+	if err != nil {
+		return nil, nil // Not a repository (or no git installed)
+	}
+	paths := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(paths) != 2 {
+		return nil, fmt.Errorf("unexpected rev-parse output %q", out)
+	}
+
+	out, err = runGit(dir, "status", "--porcelain=v2", "--branch", "--ignored", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	status := parseGitStatus(out)
+	status.Root = paths[0]
+	status.GitDir = paths[1]
+	return status, nil
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch -z` output
+func parseGitStatus(out []byte) *GitRepoStatus {
+	status := &GitRepoStatus{
+		entries: make(map[string]GitStatus),
+		dirs:    make(map[string]GitStatus),
+	}
+
+	records := strings.Split(string(out), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+
+		switch record[0] {
+		case '#':
+			parseBranchHeader(status, record)
+		case '1', '2', 'u':
+			// Ordinary, renamed and unmerged entries: fixed fields then the path
+			fieldCount := map[byte]int{'1': 9, '2': 10, 'u': 11}[record[0]]
+			fields := strings.SplitN(record, " ", fieldCount)
+			if len(fields) < fieldCount {
+				continue
+			}
+			path := fields[fieldCount-1]
+			if record[0] == '2' {
+				i++ // Renames are followed by the original path
+			}
+
+			fileStatus := GitConflicted
+			if record[0] != 'u' {
+				fileStatus = statusFromXY(fields[1])
+			}
+			status.add(path, fileStatus)
+		case '?':
+			status.add(record[2:], GitUntracked)
+		case '!':
+			status.add(record[2:], GitIgnored)
+		}
+	}
+
+	return status
+}
+
+// parseBranchHeader reads the "# branch.*" header lines
+func parseBranchHeader(status *GitRepoStatus, record string) {
+	fields := strings.Fields(record)
+	if len(fields) < 3 {
+		return
+	}
+
+	switch fields[1] {
+	case "branch.head":
+		status.Branch = fields[2]
+		if status.Branch == "(detached)" {
+			status.Branch = "HEAD (detached)"
+		}
+	case "branch.ab":
+		if len(fields) >= 4 {
+			status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+			status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+		}
+	}
+}
+
+// statusFromXY maps the staged (X) and worktree (Y) codes to a single status
+func statusFromXY(xy string) GitStatus {
+	if len(xy) != 2 {
+		return GitClean
+	}
+	if xy[1] != '.' {
+		return GitModified
+	}
+	if xy[0] != '.' {
+		return GitStaged
+	}
+	return GitClean
+}
+
+// add records a path and folds its status into every parent directory
+func (s *GitRepoStatus) add(path string, status GitStatus) {
+	s.entries[path] = status
+
+	// Ignored files do not make the directories around them interesting
+	if status == GitIgnored {
+		return
+	}
+	for dir := pathDir(path); dir != "."; dir = pathDir(dir) {
+		if s.dirs[dir] < status {
+			s.dirs[dir] = status
+		}
+	}
+}
+
+// gitStatusMsg delivers a freshly loaded repository status for dir
+type gitStatusMsg struct {
+	dir    string
+	status *GitRepoStatus
+	stamp  time.Time
+}
+
+// gitTickMsg triggers a check for working tree changes
+type gitTickMsg struct{}
+
+// gitFingerprintMsg reports when the watched files last changed
+type gitFingerprintMsg struct {
+	dir   string
+	stamp time.Time
+}
+
+// loadGitStatusCmd loads the repository status for dir off the UI goroutine
+func loadGitStatusCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		// Take the stamp first so changes made while git runs trigger another refresh
+		stamp := gitFingerprint(dir, "")
+		status, err := loadGitStatus(dir)
+		if err != nil {
+			logger.Debug("git status failed", "dir", dir, "err", err)
+		}
+		if status != nil {
+			stamp = gitFingerprint(dir, status.GitDir)
+		}
+		return gitStatusMsg{dir: dir, status: status, stamp: stamp}
+	}
+}
+
+// gitTick schedules the next change check
+func gitTick() tea.Cmd {
+	return tea.Tick(gitStatusRefreshInterval, func(time.Time) tea.Msg {
+		return gitTickMsg{}
+	})
+}
+
+// gitFingerprintCmd stats the watched files without running git
+func gitFingerprintCmd(dir, gitDir string) tea.Cmd {
+	return func() tea.Msg {
+		return gitFingerprintMsg{dir: dir, stamp: gitFingerprint(dir, gitDir)}
+	}
+}
+
+// gitFingerprint returns the latest modification time among the entries under
+// dir, down to fingerprintMaxDepth levels, and the index and HEAD of the
+// repository, which change whenever the status can. Files edited in
+// subdirectories count, since they change the status shown on directory rows.
+func gitFingerprint(dir, gitDir string) time.Time {
+	var latest time.Time
+	track := func(info os.FileInfo) {
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	seen := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries cannot be watched
+		}
+		if seen++; seen > fingerprintMaxEntries {
+			return filepath.SkipAll
+		}
+		if info, err := entry.Info(); err == nil {
+			track(info)
+		}
+		if entry.IsDir() && path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			if entry.Name() == ".git" || strings.Count(rel, string(filepath.Separator)) >= fingerprintMaxDepth-1 {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if gitDir != "" {
+		for _, name := range []string{"index", "HEAD"} {
+			if info, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+				track(info)
+			}
+		}
+	}
+
+	return latest
+}
+
+// applyGitStatus decorates list items with their status from the repository snapshot
+func applyGitStatus(items []list.Item, status *GitRepoStatus) []list.Item {
+	decorated := make([]list.Item, len(items))
+	for i, item := range items {
+		fileItem, ok := item.(FileItem)
+		if !ok || fileItem.name == ".." {
+			decorated[i] = item
+			continue
+		}
+
+		fileItem.git = GitClean
+		if status != nil && status.Contains(fileItem.path) {
+			fileItem.git = status.StatusOf(fileItem.path, fileItem.isDir)
+		}
+		decorated[i] = fileItem
+	}
+	return decorated
+}
+
+// fileDelegate renders navigator rows coloured by git status
+type fileDelegate struct {
+	list.DefaultDelegate
+	theme Theme
+}
+
+func newFileDelegate(t Theme) fileDelegate {
+	return fileDelegate{DefaultDelegate: t.listDelegate(), theme: t}
+}
+
+func (d fileDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if fileItem, ok := item.(FileItem); ok && fileItem.git != GitClean {
+		color := fileItem.git.Color(d.theme)
+		d.Styles.NormalTitle = d.Styles.NormalTitle.Foreground(color)
+		d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(color)
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	out := strings.Join([]string{
+		"# branch.oid 1f2e3d4c",
+		"# branch.head main",
+		"# branch.ab +2 -1",
+		"1 .M N... 100644 100644 100644 aaaa aaaa src/lib/util.go",
+		"1 M. N... 100644 100644 100644 aaaa bbbb docs/guide.md",
+		"2 R. N... 100644 100644 100644 aaaa aaaa R100 docs/new name.md",
+		"docs/old name.md", // The original path of the rename
+		"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.go",
+		"? notes/todo.txt",
+		"! build/",
+		"",
+	}, "\x00")
+	status := parseGitStatus([]byte(out))
+	status.Root = "/repo"
+
+	if status.Branch != "main" || status.Ahead != 2 || status.Behind != 1 {
+		t.Errorf("branch %q ↑%d ↓%d, want main ↑2 ↓1", status.Branch, status.Ahead, status.Behind)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  GitStatus
+	}{
+		{"src/lib/util.go", false, GitModified},
+		{"src", true, GitModified}, // Aggregated from the file beneath
+		{"docs/guide.md", false, GitStaged},
+		{"docs/new name.md", false, GitStaged},
+		{"docs/old name.md", false, GitClean},
+		{"docs", true, GitStaged},
+		{"conflict.go", false, GitConflicted},
+		{"notes/todo.txt", false, GitUntracked},
+		{"notes", true, GitUntracked},
+		{"build", true, GitIgnored},
+		{"build/out.o", false, GitIgnored}, // Reported once for the whole directory
+		{"main.go", false, GitClean},
+	}
+	for _, tt := range tests {
+		if got := status.StatusOf(filepath.Join("/repo", tt.path), tt.isDir); got != tt.want {
+			t.Errorf("StatusOf(%s) = %q, want %q", tt.path, got.Badge(), tt.want.Badge())
+		}
+	}
+}
+
+func TestGitFingerprintSeesNestedChanges(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "src", "lib", "util.go")
+	if err := os.MkdirAll(filepath.Dir(nested), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nested, []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	for _, path := range []string{nested, filepath.Dir(nested), filepath.Join(dir, "src"), dir} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := gitFingerprint(dir, "")
+
+	// Editing a file in place leaves the directories above it untouched
+	if err := os.Chtimes(nested, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if after := gitFingerprint(dir, ""); !after.After(before) {
+		t.Errorf("fingerprint %v did not move past %v after editing %s", after, before, nested)
+	}
+}
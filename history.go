@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// CommitItem is a commit touching the current file, shown in the history view
+type CommitItem struct {
+	hash    string
+	author  string
+	time    time.Time
+	subject string
+	path    string // Of the file in the commit from the repository root, which renames change
+}
+
+func (c CommitItem) FilterValue() string { return c.subject }
+func (c CommitItem) Title() string       { return c.shortHash() + " " + c.subject }
+func (c CommitItem) Description() string {
+	return c.author + ", " + formatAge(time.Since(c.time)) + " ago"
+}
+
+func (c CommitItem) shortHash() string {
+	if len(c.hash) > 7 {
+		return c.hash[:7]
+	}
+	return c.hash
+}
+
+// newHistoryList lists commits for the history view, sized when rendered
+func (m Model) newHistoryList(items []list.Item) list.Model {
+	l := list.New(items, m.theme.listDelegate(), m.layout.ViewportWidth, m.layout.ViewportHeight)
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowHelp(false)
+	return l
+}
+
+// loadFileHistory lists the commits touching path, newest first, following
+// renames. Each commit is listed with the file's path in it.
+func loadFileHistory(path string) ([]list.Item, error) {
+	out, err := runGit(filepath.Dir(path), "log", "--follow", "--name-only", "--format=%x01%H%x00%an%x00%at%x00%s", "--", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	return parseFileHistory(string(out)), nil
+}
+
+// parseFileHistory parses the commits of loadFileHistory, each a header line
+// starting with \x01 followed by the names of the files it changed
+func parseFileHistory(out string) []list.Item {
+	var items []list.Item
+	for _, record := range strings.Split(out, "\x01") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.SplitN(lines[0], "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		seconds, _ := strconv.ParseInt(fields[2], 10, 64)
+		commit := CommitItem{
+			hash:    fields[0],
+			author:  fields[1],
+			time:    time.Unix(seconds, 0),
+			subject: fields[3],
+		}
+		for _, name := range lines[1:] {
+			if name != "" {
+				commit.path = name
+			}
+		}
+		items = append(items, commit)
+	}
+	return items
+}
+
+// showFileAtRevision returns the content of path as of revision, where it was
+// at gitPath from the repository root, or under its current name when empty
+func showFileAtRevision(path, revision, gitPath string) ([]byte, error) {
+	object := revision + ":./" + filepath.Base(path)
+	if gitPath != "" {
+		object = revision + ":" + gitPath
+	}
+	return runGit(filepath.Dir(path), "show", object)
+}
+
+// commitPatch returns the changes a commit made to path, which was at gitPath
+// from the repository root in it, as a diff view
+func commitPatch(path, revision, gitPath string) (*diffView, error) {
+	out, err := runGit(filepath.Dir(path), "show", "--format=", "--no-color", "--no-ext-diff", revision, "--", revisionPathspec(path, gitPath))
+	if err != nil {
+		return nil, err
+	}
+	return &diffView{lines: parseUnifiedDiff(string(out))}, nil
+}
+
+// revisionPathspec names path to git as it was in a revision: by gitPath from
+// the repository root when known, or else by its current name
+func revisionPathspec(path, gitPath string) string {
+	if gitPath == "" {
+		return filepath.Base(path)
+	}
+	return ":(top)" + gitPath
+}
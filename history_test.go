@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitRepo creates a repository in a temporary directory, skipping the test
+// without git
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	initRepo(t, dir)
+	return dir
+}
+
+// initRepo creates a repository in dir, isolated from the user's git config
+func initRepo(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Alice")
+	t.Setenv("GIT_AUTHOR_EMAIL", "alice@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Alice")
+	t.Setenv("GIT_COMMITTER_EMAIL", "alice@example.com")
+	git(t, dir, "init", "-q")
+}
+
+// git runs a git command in dir, failing the test if it fails
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := runGit(dir, args...); err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+}
+
+func TestHistoryFollowsRenames(t *testing.T) {
+	repo := gitRepo(t)
+	sub := filepath.Join(repo, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "old.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "add old.txt")
+	git(t, repo, "mv", "sub/old.txt", "sub/new.txt")
+	git(t, repo, "commit", "-q", "-m", "rename to new.txt")
+
+	path := filepath.Join(sub, "new.txt")
+	items, err := loadFileHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("history has %d commits, want 2", len(items))
+	}
+	renamed, added := items[0].(CommitItem), items[1].(CommitItem)
+	if renamed.path != "sub/new.txt" || added.path != "sub/old.txt" {
+		t.Fatalf("paths = %q, %q, want the name in each commit", renamed.path, added.path)
+	}
+
+	// The commit from before the rename is shown under the name it had then
+	data, err := showFileAtRevision(path, added.hash, added.path)
+	if err != nil || string(data) != "one\n" {
+		t.Errorf("file at %s = %q, %v", added.shortHash(), data, err)
+	}
+	diff, err := commitPatch(path, added.hash, added.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.lines) == 0 || diff.lines[len(diff.lines)-1] != (DiffLine{Kind: DiffAdded, NewLine: 1, Text: "one"}) {
+		t.Errorf("patch of %s = %+v, want the added line", added.shortHash(), diff.lines)
+	}
+	msg := loadBlameCmd(path, added.hash, added.path)().(blameMsg)
+	if msg.result.err != nil || len(msg.result.lines) != 1 {
+		t.Errorf("blame at %s = %+v", added.shortHash(), msg.result)
+	}
+}
+
+func TestParseFileHistory(t *testing.T) {
+	out := "\x01abc\x00Alice\x001700000000\x00rename\n\nsub/new.txt\n" +
+		"\x01def\x00Bob\x001600000000\x00merge\n" + // Merges list no files
+		"\x01123\x00Alice\x001500000000\x00add\n\nsub/old.txt\n"
+	var got []string
+	for _, item := range parseFileHistory(out) {
+		c := item.(CommitItem)
+		got = append(got, c.hash+" "+c.author+" "+c.subject+" "+c.path)
+	}
+	want := []string{"abc Alice rename sub/new.txt", "def Bob merge ", "123 Alice add sub/old.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("commits = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("commit %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHistoryLoadsInTheBackground(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	initRepo(t, m.currentDir)
+	git(t, m.currentDir, "add", "-A")
+	git(t, m.currentDir, "commit", "-q", "-m", "first")
+	m = send(t, m, keys("j", "j", "j", "j", "enter")...) // main.go
+
+	// The view changes once git is done, not while it runs
+	updated, cmd := m.Update(keys("H")[0])
+	m = updated.(Model)
+	if m.tab().mode != FileContent || !m.tab().gitLoad.active() {
+		t.Fatalf("mode = %v while loading the history", m.tab().mode)
+	}
+	m = send(t, m, runCmd(cmd)...)
+	if m.tab().mode != HistoryContent || len(m.tab().history.Items()) != 1 {
+		t.Fatalf("mode = %v with %d commits after loading", m.tab().mode, len(m.tab().history.Items()))
+	}
+
+	// A patch still on its way when the history is closed is dropped
+	updated, cmd = m.Update(keys("p")[0])
+	m = send(t, updated.(Model), keys("H")...)
+	m = send(t, m, runCmd(cmd)...)
+	if m.tab().mode != FileContent || m.busy() {
+		t.Errorf("mode = %v after closing the history, busy %v", m.tab().mode, m.busy())
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxJumps bounds the jump list, dropping the oldest positions first
+const maxJumps = 100
+
+// markOp is what the next key names a mark for
+type markOp int
+
+const (
+	markNone       markOp = iota // Keys are handled as usual
+	markSet                      // The next key names the mark to set at the top line
+	markJump                     // The next key names the mark to jump to
+	markBookmark                 // The next key names the bookmark to set to the selected entry
+	markGoBookmark               // The next key names the bookmark to jump to
+)
+
+// jumpPosition is a place the jump list returns to: a directory in the
+// navigator, a line of the file shown and which of the two had focus
+type jumpPosition struct {
+	dir       string
+	selected  string // Selected in the navigator
+	path      string // Empty when no file was open
+	line      int
+	navigator bool
+}
+
+// lineStarts is the viewport row each line of the current tab starts on, nil
+// when rows and lines are the same, as for unwrapped or rendered content
+func (m Model) lineStarts() []int {
+	t := m.tab()
+	if t.mode != FileContent || t.isDir || m.wrapMode == WrapNone {
+		return nil
+	}
+	if m.rendersMarkdown() {
+		return nil // Lines of rendered markdown are counted as shown
+	}
+	content, _ := m.fileSource()
+	return lineStarts(content, t.showLineNumbers, m.layout.ViewportWidth, m.wrapMode)
+}
+
+// lineCount is how many lines the current tab has for go-to-line
+func (m Model) lineCount() int {
+	if starts := m.lineStarts(); starts != nil {
+		return len(starts)
+	}
+	return m.tab().viewport.TotalLineCount()
+}
+
+// topLine is the line of the file at the top of the current tab's viewport
+func (m Model) topLine() int {
+	return m.lineOfRow(m.tab().viewport.YOffset)
+}
+
+// lineOfRow is the line of the file shown on a row of the current tab
+func (m Model) lineOfRow(row int) int {
+	starts := m.lineStarts()
+	if starts == nil {
+		return row + 1
+	}
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > row })
+}
+
+// goToLine scrolls the current tab to show line at the top, or remembers the
+// line until the tab is loaded
+func (m Model) goToLine(line int) Model {
+	t := m.tab()
+	if t.path != StdinPath && t.loadedAs != t.source() {
+		t.pendingLine = line
+		return m
+	}
+	t.pendingLine = 0
+
+	line = max(1, min(line, m.lineCount()))
+	row := line - 1
+	if starts := m.lineStarts(); starts != nil {
+		row = starts[line-1]
+	}
+	offset := t.viewport.YOffset
+	t.viewport.SetYOffset(row)
+	return m.syncScroll(m.activeSplit, t.viewport.YOffset-offset)
+}
+
+// parseLineTarget reads a go-to-line entry, a line number or a percentage of
+// the lines
+func parseLineTarget(value string, lines int) (int, bool) {
+	value = strings.TrimSpace(value)
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.Atoi(percent)
+		if err != nil || p < 0 || p > 100 {
+			return 0, false
+		}
+		return max(1, (p*lines+99)/100), true
+	}
+	line, err := strconv.Atoi(value)
+	return line, err == nil && line > 0
+}
+
+// promptGoToLine asks for a line number or percentage to jump to
+func (m Model) promptGoToLine() (tea.Model, tea.Cmd) {
+	return m.openPrompt("Go to line:", "", func(m Model, value string) (tea.Model, tea.Cmd) {
+		line, ok := parseLineTarget(value, m.lineCount())
+		if !ok {
+			m.notice = fmt.Sprintf("Not a line number or percentage: %q", value)
+			return m, nil
+		}
+		m = m.recordJump()
+		return m.goToLine(line), nil
+	})
+}
+
+// handleMarkName reads the letter naming the mark or bookmark to set or jump to
+func (m Model) handleMarkName(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	op := m.pendingMark
+	m.pendingMark = markNone
+	name := msg.String()
+	if msg.Type == tea.KeyEsc {
+		return m, nil
+	}
+	if r := []rune(name); len(r) != 1 || !unicode.IsLetter(r[0]) {
+		m.notice = fmt.Sprintf("Marks are named by a letter, not %q", name)
+		return m, nil
+	}
+	if op == markBookmark || op == markGoBookmark {
+		return m.handleBookmarkName(op, name)
+	}
+
+	path := m.tab().path
+	if op == markSet {
+		if m.marks[path] == nil {
+			m.marks[path] = make(map[string]int)
+		}
+		line := m.topLine()
+		m.marks[path][name] = line
+		m.notice = fmt.Sprintf("Mark %s set at line %d", name, line)
+		return m, nil
+	}
+
+	line, ok := m.marks[path][name]
+	if !ok {
+		m.notice = fmt.Sprintf("Mark %s is not set in this file", name)
+		return m, nil
+	}
+	m = m.recordJump()
+	return m.goToLine(line), nil
+}
+
+// position is where the UI is now, for the jump list
+func (m Model) position() jumpPosition {
+	p := jumpPosition{dir: m.currentDir, navigator: m.focusedPane == NavigatorPane}
+	if item, ok := m.list.SelectedItem().(FileItem); ok {
+		p.selected = item.path
+	}
+	if m.tab().path != "" {
+		p.path, p.line = m.tab().path, m.topLine()
+	}
+	return p
+}
+
+// recordJump adds the current position to the jump list before a jump,
+// dropping the positions ctrl+o went back past
+func (m Model) recordJump() Model {
+	p := m.position()
+	jumps := m.jumps[:min(m.jumpIndex, len(m.jumps))]
+	if len(jumps) == 0 || jumps[len(jumps)-1] != p {
+		jumps = append(jumps, p)
+	}
+	if len(jumps) > maxJumps {
+		jumps = jumps[len(jumps)-maxJumps:]
+	}
+	m.jumps = jumps
+	m.jumpIndex = len(jumps)
+	return m
+}
+
+// jumpBack returns to the previous position in the jump list
+func (m Model) jumpBack() (tea.Model, tea.Cmd) {
+	if m.jumpIndex == 0 {
+		return m, nil
+	}
+	if m.jumpIndex == len(m.jumps) {
+		// Remember where we were so jumping forward comes back here
+		m = m.recordJump()
+		m.jumpIndex = len(m.jumps) - 1
+		if m.jumpIndex == 0 {
+			return m, nil
+		}
+	}
+	m.jumpIndex--
+	return m.restoreJump(m.jumps[m.jumpIndex])
+}
+
+// jumpForward undoes jumpBack
+func (m Model) jumpForward() (tea.Model, tea.Cmd) {
+	if m.jumpIndex >= len(m.jumps)-1 {
+		return m, nil
+	}
+	m.jumpIndex++
+	return m.restoreJump(m.jumps[m.jumpIndex])
+}
+
+// restoreJump shows the directory and file of p again, scrolled to its line
+func (m Model) restoreJump(p jumpPosition) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if p.dir != m.currentDir {
+		m.currentDir = p.dir
+		m.selectOnListing = p.selected
+		m, cmd = m.refreshNavigator()
+	}
+	if p.navigator || p.path == "" {
+		m = m.focusPane(NavigatorPane)
+	} else {
+		m = m.focusSplit(m.activeSplit)
+	}
+	if p.path == "" {
+		return m, cmd
+	}
+
+	if p.path == m.tab().path {
+		return m.goToLine(p.line), cmd
+	}
+	m = m.openTab(p.path)
+	m.tab().pendingLine = p.line
+	updated, renderCmd := m.rerenderCurrentFile()
+	return updated, tea.Batch(cmd, renderCmd)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLineTarget(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		ok    bool
+	}{
+		{"42", 42, true},
+		{" 7 ", 7, true},
+		{"50%", 20, true},
+		{"0%", 1, true},
+		{"100%", 40, true},
+		{"0", 0, false},
+		{"101%", 0, false},
+		{"end", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLineTarget(tt.value, 40)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseLineTarget(%q) = %d, %v; want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGoToLineAndMarksCountFileLines(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt, wrapped onto two rows a line
+	m = send(t, m, keys(":", "1", "2", "enter")...)
+	if line := m.topLine(); line != 12 {
+		t.Errorf("top line = %d after :12, want 12", line)
+	}
+	if got := frame(m); !strings.Contains(got, "12 │ a line of notes") {
+		t.Errorf("line 12 not at the top:\n%s", got)
+	}
+
+	m = send(t, m, keys("m", "a", "G", "'", "a")...)
+	if line := m.topLine(); line != 12 {
+		t.Errorf("top line = %d after jumping to mark a, want 12", line)
+	}
+
+	// Marks survive a change of wrapping since they are kept as lines of the file
+	m = send(t, m, keys("W", "W", "g", "'", "a")...)
+	if line := m.topLine(); line != 12 || m.tab().viewport.YOffset != 11 {
+		t.Errorf("top line = %d at row %d unwrapped, want 12 at row 11", line, m.tab().viewport.YOffset)
+	}
+
+	m = send(t, m, keys("'", "b")...)
+	if got := frame(m); !strings.Contains(got, "Mark b is not set") {
+		t.Errorf("no notice for an unset mark:\n%s", got)
+	}
+}
+
+func TestJumpListAcrossFiles(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt
+	m = send(t, m, keys(":", "3", "0", "enter")...)
+	notes := m.tab().path
+
+	m = send(t, m, keys("left", "k", "enter")...) // main.go
+	if strings.HasSuffix(m.tab().path, "notes.txt") {
+		t.Fatal("main.go not opened")
+	}
+
+	m = send(t, m, keys("ctrl+o")...)
+	if m.tab().path != notes || m.topLine() != 30 || m.focusedPane != NavigatorPane {
+		t.Errorf("jumped back to %s line %d, want %s line 30 with the navigator focused", m.tab().path, m.topLine(), notes)
+	}
+
+	m = send(t, m, keys("ctrl+o")...) // Before :30
+	if m.topLine() != 1 {
+		t.Errorf("top line = %d, want 1 before the first jump", m.topLine())
+	}
+
+	m = send(t, m, keys("ctrl+t", "ctrl+t")...)
+	if !strings.HasSuffix(m.tab().path, "main.go") {
+		t.Errorf("jumping forward ended in %s, want main.go", m.tab().path)
+	}
+	assertFits(t, m, 80, 20)
+}
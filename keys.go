@@ -0,0 +1,467 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyScope is where a binding applies; bindings in overlapping scopes must not share keys
+type keyScope int
+
+const (
+	scopeGlobal    keyScope = iota // Any pane
+	scopeNavigator                 // Only while the navigator is focused
+	scopeContent                   // Only while a content split is focused
+)
+
+func (s keyScope) overlaps(other keyScope) bool {
+	return s == scopeGlobal || other == scopeGlobal || s == other
+}
+
+// KeyMap holds every configurable binding. A key may be a sequence of key
+// names separated by spaces, like "g g".
+type KeyMap struct {
+	Quit          key.Binding
+	PaneSelection key.Binding // Also leaves fullscreen
+
+	// Movement within the focused pane
+	Up           key.Binding
+	Down         key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+	Left         key.Binding // Back to the navigator, up a directory in the Miller layout, or scrolls unwrapped content
+	Right        key.Binding // Into the selection in the Miller layout, or scrolls unwrapped content
+	Open         key.Binding
+
+	// Panes and layout
+	NextPane        key.Binding
+	ShrinkNavigator key.Binding
+	GrowNavigator   key.Binding
+	ToggleNavigator key.Binding
+	ToggleLayout    key.Binding
+	ScrollLock      key.Binding
+	NextTab         key.Binding
+	PrevTab         key.Binding
+	Diff            key.Binding // Diffs the marked file from the navigator, HEAD from content
+	LogPanel        key.Binding // Recent log entries, for troubleshooting; left out of the help line
+	JumpBack        key.Binding // To the position before the last jump, across files and directories
+	JumpForward     key.Binding // ctrl+t rather than vim's ctrl+i, which terminals send as tab
+	CopyLocation    key.Binding // The selected path, or path:line of the content shown
+	JumpToBookmark  key.Binding // Followed by the bookmark's letter
+	Bookmarks       key.Binding // Lists the bookmarks in the navigator
+	JumpPrompt      key.Binding // To the most frecent visited path matching some words
+
+	// Navigator
+	Back           key.Binding
+	Mark           key.Binding
+	Bookmark       key.Binding // Followed by a letter naming a bookmark for the selected entry
+	RemoveBookmark key.Binding // In the bookmark list
+
+	// Content
+	ToggleLineNumbers key.Binding
+	ToggleWrap        key.Binding // Cycles word, character and no wrapping
+	Fullscreen        key.Binding
+	SplitVertical     key.Binding
+	SplitHorizontal   key.Binding
+	CloseSplit        key.Binding
+	CloseTab          key.Binding
+	Blame             key.Binding
+	History           key.Binding
+	Patch             key.Binding
+	DiffRevision      key.Binding
+	SideBySide        key.Binding
+	NextHunk          key.Binding
+	PrevHunk          key.Binding
+	GoToLine          key.Binding // A line number or percentage
+	SetMark           key.Binding // Followed by a letter naming the mark
+	JumpToMark        key.Binding
+	Visual            key.Binding // Selects characters from the top line, then moved with the movement keys
+	VisualLine        key.Binding
+	CopySelection     key.Binding
+	ToggleOutline     key.Binding // Headings of a markdown file, beside it
+	NextLink          key.Binding // Focuses links of rendered markdown, followed with Open
+	PrevLink          key.Binding
+	ToggleRaw         key.Binding // Markdown source instead of the rendering
+}
+
+// namedBinding is a binding with its name in the config file
+type namedBinding struct {
+	name    string
+	scope   keyScope
+	binding *key.Binding
+}
+
+// bindings lists every binding with its config name and scope
+func (k *KeyMap) bindings() []namedBinding {
+	return []namedBinding{
+		{"quit", scopeGlobal, &k.Quit},
+		{"pane_selection", scopeGlobal, &k.PaneSelection},
+		{"up", scopeGlobal, &k.Up},
+		{"down", scopeGlobal, &k.Down},
+		{"page_up", scopeGlobal, &k.PageUp},
+		{"page_down", scopeGlobal, &k.PageDown},
+		{"half_page_up", scopeGlobal, &k.HalfPageUp},
+		{"half_page_down", scopeGlobal, &k.HalfPageDown},
+		{"top", scopeGlobal, &k.Top},
+		{"bottom", scopeGlobal, &k.Bottom},
+		{"left", scopeGlobal, &k.Left},
+		{"right", scopeGlobal, &k.Right},
+		{"open", scopeGlobal, &k.Open},
+		{"next_pane", scopeGlobal, &k.NextPane},
+		{"shrink_navigator", scopeGlobal, &k.ShrinkNavigator},
+		{"grow_navigator", scopeGlobal, &k.GrowNavigator},
+		{"toggle_navigator", scopeGlobal, &k.ToggleNavigator},
+		{"toggle_layout", scopeGlobal, &k.ToggleLayout},
+		{"scroll_lock", scopeGlobal, &k.ScrollLock},
+		{"next_tab", scopeGlobal, &k.NextTab},
+		{"prev_tab", scopeGlobal, &k.PrevTab},
+		{"diff", scopeGlobal, &k.Diff},
+		{"log_panel", scopeGlobal, &k.LogPanel},
+		{"jump_back", scopeGlobal, &k.JumpBack},
+		{"jump_forward", scopeGlobal, &k.JumpForward},
+		{"copy_location", scopeGlobal, &k.CopyLocation},
+		{"jump_to_bookmark", scopeGlobal, &k.JumpToBookmark},
+		{"bookmarks", scopeGlobal, &k.Bookmarks},
+		{"jump_prompt", scopeGlobal, &k.JumpPrompt},
+		{"back", scopeNavigator, &k.Back},
+		{"mark", scopeNavigator, &k.Mark},
+		{"bookmark", scopeNavigator, &k.Bookmark},
+		{"remove_bookmark", scopeNavigator, &k.RemoveBookmark},
+		{"toggle_line_numbers", scopeContent, &k.ToggleLineNumbers},
+		{"toggle_wrap", scopeContent, &k.ToggleWrap},
+		{"fullscreen", scopeContent, &k.Fullscreen},
+		{"split_vertical", scopeContent, &k.SplitVertical},
+		{"split_horizontal", scopeContent, &k.SplitHorizontal},
+		{"close_split", scopeContent, &k.CloseSplit},
+		{"close_tab", scopeContent, &k.CloseTab},
+		{"blame", scopeContent, &k.Blame},
+		{"history", scopeContent, &k.History},
+		{"patch", scopeContent, &k.Patch},
+		{"diff_revision", scopeContent, &k.DiffRevision},
+		{"side_by_side", scopeContent, &k.SideBySide},
+		{"next_hunk", scopeContent, &k.NextHunk},
+		{"prev_hunk", scopeContent, &k.PrevHunk},
+		{"go_to_line", scopeContent, &k.GoToLine},
+		{"set_mark", scopeContent, &k.SetMark},
+		{"jump_to_mark", scopeContent, &k.JumpToMark},
+		{"visual", scopeContent, &k.Visual},
+		{"visual_line", scopeContent, &k.VisualLine},
+		{"copy_selection", scopeContent, &k.CopySelection},
+		{"toggle_outline", scopeContent, &k.ToggleOutline},
+		{"next_link", scopeContent, &k.NextLink},
+		{"prev_link", scopeContent, &k.PrevLink},
+		{"toggle_raw", scopeContent, &k.ToggleRaw},
+	}
+}
+
+// binding creates a binding whose help shows its first two keys
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKeys(keys), desc))
+}
+
+// rebind replaces the keys of a binding, keeping its description
+func rebind(b *key.Binding, keys ...string) {
+	*b = binding(b.Help().Desc, keys...)
+}
+
+// helpKeys renders up to two keys for the help line, e.g. "q/ctrl+c"
+func helpKeys(keys []string) string {
+	var shown []string
+	for _, k := range keys[:min(2, len(keys))] {
+		shown = append(shown, displayKey(k))
+	}
+	return strings.Join(shown, "/")
+}
+
+// displayKey renders a key name compactly, e.g. arrows as symbols
+func displayKey(k string) string {
+	switch k {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	case " ":
+		return "space"
+	}
+	return k
+}
+
+// DefaultKeyMap returns the built-in bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:          binding("quit", "q", "ctrl+c"),
+		PaneSelection: binding("pane selection", "esc"),
+
+		Up:           binding("up", "up", "k"),
+		Down:         binding("down", "down", "j"),
+		PageUp:       binding("page up", "pgup"),
+		PageDown:     binding("page down", "pgdown", " "),
+		HalfPageUp:   binding("half page up", "ctrl+u"),
+		HalfPageDown: binding("half page down", "ctrl+d"),
+		Top:          binding("top", "home", "g"),
+		Bottom:       binding("bottom", "end", "G"),
+		Left:         binding("back to navigator", "left"),
+		Right:        binding("open", "right"),
+		Open:         binding("select", "enter"),
+
+		NextPane:        binding("next pane", "ctrl+w"),
+		ShrinkNavigator: binding("shrink navigator", "<"),
+		GrowNavigator:   binding("grow navigator", ">"),
+		ToggleNavigator: binding("hide navigator", "ctrl+b"),
+		ToggleLayout:    binding("layout", "M"),
+		ScrollLock:      binding("lock scroll", "L"),
+		NextTab:         binding("next tab", "tab"),
+		PrevTab:         binding("previous tab", "shift+tab"),
+		Diff:            binding("diff", "d"),
+		LogPanel:        binding("log", "f12"),
+		JumpBack:        binding("jump back", "ctrl+o"),
+		JumpForward:     binding("jump forward", "ctrl+t"),
+		CopyLocation:    binding("copy path", "Y"),
+		JumpToBookmark:  binding("jump to bookmark", "`"),
+		Bookmarks:       binding("bookmarks", "B"),
+		JumpPrompt:      binding("jump to visited", "J"),
+
+		Back:           binding("back", "z"),
+		Mark:           binding("mark", "m"),
+		Bookmark:       binding("bookmark", "b"),
+		RemoveBookmark: binding("remove bookmark", "x"),
+
+		ToggleLineNumbers: binding("toggle line numbers", "l"),
+		ToggleWrap:        binding("wrap", "W"),
+		Fullscreen:        binding("fullscreen", "f"),
+		SplitVertical:     binding("split, arranging all splits side by side", "|"),
+		SplitHorizontal:   binding("split, stacking all splits", "_"),
+		CloseSplit:        binding("close split", "x"),
+		CloseTab:          binding("close tab", "w"),
+		Blame:             binding("blame", "b"),
+		History:           binding("history", "H"),
+		Patch:             binding("patch", "p"),
+		DiffRevision:      binding("diff revision", "r"),
+		SideBySide:        binding("side-by-side", "s"),
+		NextHunk:          binding("next hunk", "n"),
+		PrevHunk:          binding("previous hunk", "N"),
+		GoToLine:          binding("go to line", ":"),
+		SetMark:           binding("set mark", "m"),
+		JumpToMark:        binding("jump to mark", "'"),
+		Visual:            binding("select", "v"),
+		VisualLine:        binding("select lines", "V"),
+		CopySelection:     binding("copy", "y"),
+		ToggleOutline:     binding("outline", "o"),
+		NextLink:          binding("next link", "}"),
+		PrevLink:          binding("previous link", "{"),
+		ToggleRaw:         binding("source", "R"),
+	}
+}
+
+// keyMapPresets adapt the default bindings to the movement keys of other programs
+var keyMapPresets = map[string]func(*KeyMap){
+	"default": func(*KeyMap) {},
+	"vim": func(k *KeyMap) {
+		rebind(&k.Left, "h", "left")
+		rebind(&k.Right, "l", "right")
+		rebind(&k.Top, "g g", "home")
+		rebind(&k.Bottom, "G", "end")
+		rebind(&k.HalfPageUp, "ctrl+u")
+		rebind(&k.HalfPageDown, "ctrl+d")
+		rebind(&k.ToggleLineNumbers, "#") // l moves right
+	},
+	"emacs": func(k *KeyMap) {
+		rebind(&k.Quit, "ctrl+x ctrl+c", "q", "ctrl+c")
+		rebind(&k.Up, "ctrl+p", "up")
+		rebind(&k.Down, "ctrl+n", "down")
+		rebind(&k.Left, "ctrl+b", "left")
+		rebind(&k.Right, "ctrl+f", "right")
+		rebind(&k.PageUp, "alt+v", "pgup")
+		rebind(&k.PageDown, "ctrl+v", "pgdown")
+		rebind(&k.HalfPageUp)
+		rebind(&k.HalfPageDown)
+		rebind(&k.Top, "alt+<", "home")
+		rebind(&k.Bottom, "alt+>", "end")
+		rebind(&k.NextPane, "ctrl+x o", "ctrl+w")
+		rebind(&k.ToggleNavigator, "ctrl+x d") // ctrl+b moves left
+		rebind(&k.SplitVertical, "ctrl+x 3", "|")
+		rebind(&k.SplitHorizontal, "ctrl+x 2", "_")
+		rebind(&k.CloseSplit, "ctrl+x 0", "x")
+		rebind(&k.CloseTab, "ctrl+x k", "w")
+		rebind(&k.CopySelection, "alt+w", "y")
+	},
+	"less": func(k *KeyMap) {
+		rebind(&k.Up, "k", "y", "up", "ctrl+p")
+		rebind(&k.Down, "j", "e", "down", "ctrl+n")
+		rebind(&k.PageUp, "b", "pgup", "alt+v")
+		rebind(&k.PageDown, " ", "f", "pgdown", "ctrl+v")
+		rebind(&k.HalfPageUp, "u", "ctrl+u")
+		rebind(&k.HalfPageDown, "d", "ctrl+d")
+		rebind(&k.Top, "g", "<", "home")
+		rebind(&k.Bottom, "G", ">", "end")
+
+		// Move the bindings less uses for movement out of the way
+		rebind(&k.Blame, "B")
+		rebind(&k.Fullscreen, "F")
+		rebind(&k.Diff, "D")
+		rebind(&k.ShrinkNavigator, "[")
+		rebind(&k.GrowNavigator, "]")
+		rebind(&k.CopySelection, "c") // y moves up
+		rebind(&k.Bookmark, "a")      // b pages up
+		rebind(&k.Bookmarks, "A")     // B blames
+	},
+}
+
+// keyMapPresetNames lists the presets for messages and flag help
+func keyMapPresetNames() []string {
+	names := make([]string, 0, len(keyMapPresets))
+	for name := range keyMapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewKeyMap builds a keymap from a preset and per-binding overrides by config
+// name, failing on unknown names or conflicting keys
+func NewKeyMap(preset string, overrides map[string][]string) (KeyMap, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	apply, ok := keyMapPresets[preset]
+	if !ok {
+		return KeyMap{}, fmt.Errorf("unknown keymap %q (want one of %s)", preset, strings.Join(keyMapPresetNames(), ", "))
+	}
+
+	keys := DefaultKeyMap()
+	apply(&keys)
+
+	byName := make(map[string]*key.Binding)
+	for _, nb := range keys.bindings() {
+		byName[nb.name] = nb.binding
+	}
+	for name, keyNames := range overrides {
+		b, ok := byName[name]
+		if !ok {
+			return KeyMap{}, fmt.Errorf("unknown key binding %q", name)
+		}
+		for i, k := range keyNames {
+			if k == "space" {
+				keyNames[i] = " "
+			}
+		}
+		rebind(b, keyNames...)
+	}
+
+	return keys, keys.validate()
+}
+
+// validate reports bindings that share a key, or where one binding's key starts
+// another's sequence, in scopes that can be active at the same time
+func (k *KeyMap) validate() error {
+	all := k.bindings()
+	var conflicts []string
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if !a.scope.overlaps(b.scope) {
+				continue
+			}
+			for _, ka := range a.binding.Keys() {
+				for _, kb := range b.binding.Keys() {
+					if ka == kb || strings.HasPrefix(kb, ka+" ") || strings.HasPrefix(ka, kb+" ") {
+						conflicts = append(conflicts, fmt.Sprintf("%s (%q) and %s (%q)", a.name, ka, b.name, kb))
+					}
+				}
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting key bindings: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// isPrefix reports whether seq starts a longer binding, so more keys should be awaited
+func (k *KeyMap) isPrefix(seq string) bool {
+	for _, nb := range k.bindings() {
+		for _, kb := range nb.binding.Keys() {
+			if strings.HasPrefix(kb, seq+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyPress is a key or a completed key sequence, matched against bindings with key.Matches
+type keyPress string
+
+func (k keyPress) String() string { return string(k) }
+
+// readKey combines msg with any keys already typed towards a sequence. It
+// reports false while a sequence is still incomplete.
+func (m Model) readKey(msg tea.KeyMsg) (Model, keyPress, bool) {
+	seq := msg.String()
+	if m.keyPrefix != "" {
+		seq = m.keyPrefix + " " + seq
+		m.keyPrefix = ""
+	}
+	if m.keys.isPrefix(seq) {
+		m.keyPrefix = seq
+		return m, "", false
+	}
+	return m, keyPress(seq), true
+}
+
+// moveList moves a list's cursor for a movement key
+func (k *KeyMap) moveList(l *list.Model, press keyPress) {
+	switch {
+	case key.Matches(press, k.Up):
+		l.CursorUp()
+	case key.Matches(press, k.Down):
+		l.CursorDown()
+	case key.Matches(press, k.PageUp):
+		l.PrevPage()
+	case key.Matches(press, k.PageDown):
+		l.NextPage()
+	case key.Matches(press, k.HalfPageUp):
+		l.Select(max(0, l.Index()-l.Paginator.PerPage/2))
+	case key.Matches(press, k.HalfPageDown):
+		l.Select(min(len(l.VisibleItems())-1, l.Index()+l.Paginator.PerPage/2))
+	case key.Matches(press, k.Top):
+		l.Select(0)
+	case key.Matches(press, k.Bottom):
+		l.Select(len(l.VisibleItems()) - 1)
+	}
+}
+
+// moveViewport scrolls a viewport for a movement key
+func (k *KeyMap) moveViewport(vp *viewport.Model, press keyPress) {
+	switch {
+	case key.Matches(press, k.Up):
+		vp.ScrollUp(1)
+	case key.Matches(press, k.Down):
+		vp.ScrollDown(1)
+	case key.Matches(press, k.PageUp):
+		vp.PageUp()
+	case key.Matches(press, k.PageDown):
+		vp.PageDown()
+	case key.Matches(press, k.HalfPageUp):
+		vp.HalfPageUp()
+	case key.Matches(press, k.HalfPageDown):
+		vp.HalfPageDown()
+	case key.Matches(press, k.Top):
+		vp.GotoTop()
+	case key.Matches(press, k.Bottom):
+		vp.GotoBottom()
+	}
+}
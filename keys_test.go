@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyMapPresetsHaveNoConflicts(t *testing.T) {
+	for _, name := range keyMapPresetNames() {
+		if _, err := NewKeyMap(name, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestKeyMapOverrides(t *testing.T) {
+	k, err := NewKeyMap("vim", map[string][]string{"blame": {"ctrl+g"}, "page_down": {"space"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := k.Blame.Keys(); !reflect.DeepEqual(got, []string{"ctrl+g"}) {
+		t.Errorf("blame keys = %q, want the override", got)
+	}
+	if got := k.PageDown.Keys(); !reflect.DeepEqual(got, []string{" "}) {
+		t.Errorf("page_down keys = %q, want space", got)
+	}
+	if got := k.Left.Keys(); got[0] != "h" {
+		t.Errorf("left keys = %q, want the vim preset's", got)
+	}
+
+	if _, err := NewKeyMap("nano", nil); err == nil {
+		t.Error("unknown preset accepted")
+	}
+	if _, err := NewKeyMap("default", map[string][]string{"teleport": {"t"}}); err == nil {
+		t.Error("unknown binding name accepted")
+	}
+}
+
+func TestKeyMapRejectsConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		conflict  string // Empty when the keymap is valid
+	}{
+		{"same key", map[string][]string{"blame": {"q"}}, "quit"},
+		{"prefix of a sequence", map[string][]string{"blame": {"g x"}}, "top"},
+		{"separate scopes", map[string][]string{"blame": {"z"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyMap("default", tt.overrides)
+			switch {
+			case tt.conflict == "" && err != nil:
+				t.Errorf("valid keymap rejected: %v", err)
+			case tt.conflict != "" && (err == nil || !strings.Contains(err.Error(), tt.conflict)):
+				t.Errorf("err = %v, want a conflict with %s", err, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestReadKeySequences(t *testing.T) {
+	press := func(m Model, r string) (Model, keyPress, bool) {
+		return m.readKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r)})
+	}
+
+	// Without a sequence starting with g, g is complete on its own
+	m := Model{keys: DefaultKeyMap()}
+	if _, k, ok := press(m, "g"); !ok || !key.Matches(k, m.keys.Top) {
+		t.Errorf("g = %q, %v; want top", k, ok)
+	}
+
+	vim, err := NewKeyMap("vim", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = Model{keys: vim}
+	m, k, ok := press(m, "g")
+	if ok || m.keyPrefix != "g" {
+		t.Fatalf("g = %q, %v with prefix %q; want to wait for the sequence", k, ok, m.keyPrefix)
+	}
+	m, k, ok = press(m, "g")
+	if !ok || !key.Matches(k, m.keys.Top) || m.keyPrefix != "" {
+		t.Errorf("g g = %q, %v with prefix %q; want top", k, ok, m.keyPrefix)
+	}
+
+	// A key that does not continue the sequence ends it, matching nothing
+	m, _, _ = press(m, "g")
+	if _, k, ok = press(m, "j"); !ok || key.Matches(k, m.keys.Down) {
+		t.Errorf("g j = %q, %v; want an unbound sequence", k, ok)
+	}
+}
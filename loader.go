@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+const (
+	// loadTimeout is how long a read or listing may take before giving up on it
+	loadTimeout = 10 * time.Second
+	// MaxFileSize is the largest file opened, since rendering more would stall the UI
+	MaxFileSize = 10 << 20
+)
+
+// errLoadTimeout is returned for loads that did not finish within loadTimeout
+var errLoadTimeout = errors.New("timed out")
+
+// fileTooLargeError is returned for files over MaxFileSize
+type fileTooLargeError struct {
+	size int64
+}
+
+func (e *fileTooLargeError) Error() string {
+	return fmt.Sprintf("file is %s, over the %s limit", formatSize(e.size), formatSize(MaxFileSize))
+}
+
+// lastLoadID numbers load requests so results can be matched to them
+var lastLoadID atomic.Int64
+
+// loadRequest tracks an in-flight load. A result whose id no longer matches
+// belongs to a load that was superseded or cancelled, and is dropped.
+type loadRequest struct {
+	id     int64 // Zero while nothing is loading
+	cancel context.CancelFunc
+}
+
+// begin cancels any load r tracks and starts a new one, returning the context
+// the load runs under
+func (r *loadRequest) begin() context.Context {
+	r.end()
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	r.id = lastLoadID.Add(1)
+	r.cancel = cancel
+	return ctx
+}
+
+// end stops tracking the load, cancelling it if it is still running
+func (r *loadRequest) end() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	*r = loadRequest{}
+}
+
+// active reports whether a load is in flight
+func (r loadRequest) active() bool {
+	return r.id != 0
+}
+
+// fileLoadedMsg carries a tab's file content, or directory entries in the Miller preview
+type fileLoadedMsg struct {
+	id      int64
+	file    loadedFile
+	entries []filebrowser.Entry
+	err     error
+}
+
+// loadedFile is the content of a file and when it was last modified, zero for revisions
+type loadedFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// gitLoadedMsg carries a tab's file history or diff, computed by git in the background
+type gitLoadedMsg struct {
+	id      int64
+	mode    ContentMode // What the tab shows once loaded: HistoryContent or DiffContent
+	what    string      // What git was doing, for the error shown instead
+	history []list.Item
+	diff    *diffView
+	err     error
+}
+
+// dirListedMsg carries the entries of a directory for the navigator or the parent column
+type dirListedMsg struct {
+	id      int64
+	dir     string
+	entries []filebrowser.Entry
+	err     error
+}
+
+// withContext runs load until it finishes or ctx is done. Reads cannot be
+// interrupted, so an abandoned load finishes in the background and is ignored.
+func withContext[T any](ctx context.Context, load func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := load()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, errLoadTimeout
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// readFile reads a file from disk, refusing files over MaxFileSize, or from git
+// when a revision is given, where the file was at gitPath
+func readFile(path, revision, gitPath string) (loadedFile, error) {
+	if revision != "" {
+		data, err := showFileAtRevision(path, revision, gitPath)
+		return loadedFile{data: data}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return loadedFile{}, err
+	}
+	if info.Size() > MaxFileSize {
+		return loadedFile{}, &fileTooLargeError{size: info.Size()}
+	}
+	data, err := os.ReadFile(path)
+	return loadedFile{data: data, modTime: info.ModTime()}, err
+}
+
+// loadTabCmd reads the file, or lists the directory, shown by t
+func (m Model) loadTabCmd(t *Tab) tea.Cmd {
+	ctx := t.load.begin()
+	t.loadingAs = t.source()
+	t.stale = false
+	id, path, revision, gitPath, isDir := t.load.id, t.path, t.revision, t.revisionPath, t.isDir
+	root, opts := m.rootDir, m.listing
+
+	return func() tea.Msg {
+		msg := fileLoadedMsg{id: id}
+		if isDir {
+			msg.entries, msg.err = withContext(ctx, func() ([]filebrowser.Entry, error) {
+				return filebrowser.ReadDir(path, root, opts)
+			})
+		} else {
+			msg.file, msg.err = withContext(ctx, func() (loadedFile, error) {
+				return readFile(path, revision, gitPath)
+			})
+		}
+		return msg
+	}
+}
+
+// listDirCmd lists dir for the load tracked by r
+func (m Model) listDirCmd(r *loadRequest, dir string) tea.Cmd {
+	ctx := r.begin()
+	id, root, opts := r.id, m.rootDir, m.listing
+
+	return func() tea.Msg {
+		entries, err := withContext(ctx, func() ([]filebrowser.Entry, error) {
+			return filebrowser.ReadDir(dir, root, opts)
+		})
+		return dirListedMsg{id: id, dir: dir, entries: entries, err: err}
+	}
+}
+
+// loadHistoryCmd lists the commits touching the file of t
+func loadHistoryCmd(t *Tab) tea.Cmd {
+	ctx := t.gitLoad.begin()
+	id, path := t.gitLoad.id, t.path
+
+	return func() tea.Msg {
+		items, err := withContext(ctx, func() ([]list.Item, error) {
+			return loadFileHistory(path)
+		})
+		return gitLoadedMsg{id: id, mode: HistoryContent, what: "loading history", history: items, err: err}
+	}
+}
+
+// loadDiffCmd computes a diff for t with load, what describing it for errors
+func loadDiffCmd(t *Tab, what string, load func() (*diffView, error)) tea.Cmd {
+	ctx := t.gitLoad.begin()
+	id := t.gitLoad.id
+
+	return func() tea.Msg {
+		diff, err := withContext(ctx, load)
+		return gitLoadedMsg{id: id, mode: DiffContent, what: what, diff: diff, err: err}
+	}
+}
+
+// handleFileLoaded stores a tab's loaded content and renders it if the tab is showing
+func (m Model) handleFileLoaded(msg fileLoadedMsg) (tea.Model, tea.Cmd) {
+	for i, s := range m.splits {
+		for _, t := range s.tabs {
+			if t.load.id != msg.id {
+				continue
+			}
+			t.load.end()
+			t.data, t.modTime, t.entries, t.loadErr = msg.file.data, msg.file.modTime, msg.entries, msg.err
+			if t.loadedAs != t.loadingAs {
+				t.xOffset = 0 // Another file, so start at its left edge
+				t.focusedLink = 0
+			}
+			t.loadedAs = t.loadingAs
+			if msg.err != nil {
+				logger.Warn("loading failed", "path", t.path, "revision", t.revision, "err", msg.err)
+			}
+			if t == s.tab() {
+				return m.rerenderSplit(i)
+			}
+			return m, nil
+		}
+	}
+	return m, nil // Superseded, or its tab was closed
+}
+
+// handleGitLoaded shows a tab's history or diff, or why git could not compute it
+func (m Model) handleGitLoaded(msg gitLoadedMsg) (tea.Model, tea.Cmd) {
+	for i, s := range m.splits {
+		for _, t := range s.tabs {
+			if t.gitLoad.id != msg.id {
+				continue
+			}
+			t.gitLoad.end()
+			if msg.err != nil {
+				logger.Warn(msg.what+" failed", "path", t.path, "err", msg.err)
+				t.content = fmt.Sprintf("Error %s: %v", msg.what, msg.err)
+				t.viewport.SetContent(t.content)
+				return m, nil
+			}
+			switch msg.mode {
+			case HistoryContent:
+				t.history = m.newHistoryList(msg.history)
+			case DiffContent:
+				t.diff = msg.diff
+				t.revision = ""
+			}
+			t.mode = msg.mode
+			t.viewport.GotoTop()
+			if t == s.tab() {
+				return m.rerenderSplit(i)
+			}
+			return m, nil
+		}
+	}
+	return m, nil // Superseded, or its tab was closed
+}
+
+// busy reports whether anything is loading, which keeps the spinner turning
+func (m Model) busy() bool {
+	if m.navigatorLoad.active() || m.parentLoad.active() {
+		return true
+	}
+	for _, s := range m.splits {
+		for _, t := range s.tabs {
+			if t.load.active() || t.gitLoad.active() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withSpinner adds starting the spinner to cmd, unless it is already turning
+func (m Model) withSpinner(cmd tea.Cmd) (Model, tea.Cmd) {
+	if m.spinning {
+		return m, cmd
+	}
+	m.spinning = true
+	return m, tea.Batch(cmd, m.spinner.Tick)
+}
+
+// loadErrorView explains why a file or directory could not be shown
+func (m Model) loadErrorView(err error, path string, width int) string {
+	name := filepath.Base(path)
+	var tooLarge *fileTooLargeError
+	var title, detail string
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		title = "Permission denied"
+		detail = fmt.Sprintf("You do not have permission to read %s.", name)
+	case errors.Is(err, fs.ErrNotExist):
+		title = "Not found"
+		detail = fmt.Sprintf("%s no longer exists. It may have been moved or deleted.", name)
+	case errors.As(err, &tooLarge):
+		title = "Too large"
+		detail = fmt.Sprintf("%s is %s, over the %s limit for viewing.", name, formatSize(tooLarge.size), formatSize(MaxFileSize))
+	case errors.Is(err, errLoadTimeout):
+		title = "Timed out"
+		detail = fmt.Sprintf("Reading %s took longer than %s. The disk or network share may be slow or unavailable.", name, loadTimeout)
+	default:
+		title = "Could not read " + name
+		detail = err.Error()
+	}
+
+	heading := lipgloss.NewStyle().Bold(true).Foreground(m.theme.DiffRemoved).Render(title)
+	if width > 0 {
+		detail = wordwrap.String(detail, width)
+	}
+	return heading + "\n\n" + detail
+}
+
+// formatSize renders a byte count for messages, e.g. "12.5 MB"
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// press sends a key without running the commands it returns, so loads stay in flight
+func press(m Model, name string) (Model, tea.Cmd) {
+	updated, cmd := m.Update(keys(name)[0])
+	return updated.(Model), cmd
+}
+
+func TestLoadingShowsSpinnerUntilResult(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j")...) // main.go
+	m, cmd := press(m, "enter")
+
+	got := frame(m)
+	if !strings.Contains(got, "Loading…") || !strings.Contains(got, "main.go "+m.spinner.View()) {
+		t.Errorf("no loading state while reading:\n%s", got)
+	}
+
+	m = send(t, m, runCmd(cmd)...)
+	if got := frame(m); !strings.Contains(got, "package main") || strings.Contains(got, m.spinner.View()) {
+		t.Errorf("file not shown once read:\n%s", got)
+	}
+}
+
+func TestStaleResultsAreDropped(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j")...) // main.go
+	m, first := press(m, "enter")
+	m = send(t, m, keys("left")...)
+	m, second := press(m, "enter") // Reopening supersedes the first read
+	firstMsgs := runCmd(first)
+
+	if err := os.WriteFile(m.tab().path, []byte("rewritten\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = send(t, m, runCmd(second)...)
+	m = send(t, m, firstMsgs...)
+	if got := frame(m); !strings.Contains(got, "rewritten") {
+		t.Errorf("the superseded read replaced the newer one:\n%s", got)
+	}
+
+	// Going back before a directory is listed drops its listing
+	m = send(t, m, keys("left", "g", "j")...) // docs
+	root := m.currentDir
+	m, enterDocs := press(m, "enter")
+	m, back := press(m, "z")
+	m = send(t, m, runCmd(back)...)
+	m = send(t, m, runCmd(enterDocs)...)
+	if m.currentDir != root {
+		t.Errorf("currentDir = %s, want %s", m.currentDir, root)
+	}
+	if item, _ := m.list.SelectedItem().(FileItem); item.path != filepath.Join(root, "..") && filepath.Dir(item.path) != root {
+		t.Errorf("navigator shows %s, not the entries of %s", item.path, root)
+	}
+}
+
+func TestLoadErrorViews(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, path string)
+		want    string
+	}{
+		{"not_found", func(t *testing.T, path string) {
+			os.Remove(path)
+		}, "Not found"},
+		{"too_large", func(t *testing.T, path string) {
+			if err := os.Truncate(path, MaxFileSize+1); err != nil {
+				t.Fatal(err)
+			}
+		}, "Too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, 80, 20)
+			m = send(t, m, keys("j", "j", "j", "j")...) // main.go
+			m, cmd := press(m, "enter")
+			tt.prepare(t, m.tab().path)
+			m = send(t, m, runCmd(cmd)...)
+
+			if got := frame(m); !strings.Contains(got, tt.want) {
+				t.Errorf("want %q in:\n%s", tt.want, got)
+			}
+			assertFits(t, m, 80, 20)
+		})
+	}
+}
+
+func TestNavigatorListingError(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j")...) // docs
+	os.RemoveAll(filepath.Join(m.currentDir, "docs"))
+	m = send(t, m, keys("enter")...)
+
+	if got := frame(m); !strings.Contains(got, "Not found") {
+		t.Errorf("listing error not shown:\n%s", got)
+	}
+	assertFits(t, m, 80, 20)
+}
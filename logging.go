@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// logPanelSize is how many recent entries the log panel keeps
+const logPanelSize = 500
+
+// logger receives the app's diagnostics. Nothing is written to disk unless a
+// log file is configured; recent entries are kept for the log panel either way.
+var logger = slog.New(slog.DiscardHandler)
+
+// logOptions configure where and how much is logged
+type logOptions struct {
+	file       string // Empty to only keep entries for the log panel
+	level      string // debug, info, warn or error
+	format     string // text or json
+	maxSize    int64  // Rotate the file once it would grow past this many bytes
+	maxBackups int    // Rotated files kept as file.1, file.2, …
+}
+
+// envOr returns the value of the environment variable name, or fallback when it is unset
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// parseLogLevel maps a level name to a slog level
+func parseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+	}
+	return level, nil
+}
+
+// setupLogging points logger at the log file, if any, and at the returned
+// buffer of recent entries. The returned function closes the file.
+func setupLogging(opts logOptions) (*logBuffer, func() error, error) {
+	level, err := parseLogLevel(opts.level)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.format != "text" && opts.format != "json" {
+		return nil, nil, fmt.Errorf("unknown log format %q (want text or json)", opts.format)
+	}
+
+	buffer := newLogBuffer(logPanelSize)
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	handlers := []slog.Handler{slog.NewTextHandler(buffer, handlerOpts)}
+	closeFile := func() error { return nil }
+
+	if opts.file != "" {
+		file, err := openRotatingFile(opts.file, opts.maxSize, opts.maxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening log file: %w", err)
+		}
+		closeFile = file.Close
+		if opts.format == "json" {
+			handlers = append(handlers, slog.NewJSONHandler(file, handlerOpts))
+		} else {
+			handlers = append(handlers, slog.NewTextHandler(file, handlerOpts))
+		}
+	}
+
+	logger = slog.New(teeHandler(handlers))
+	return buffer, closeFile, nil
+}
+
+// teeHandler sends each record to every handler that accepts its level
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// logBuffer keeps the most recent log lines for the log panel. slog handlers
+// write one line per record, so each write is an entry.
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []string
+	size    int
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{size: size}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, strings.TrimRight(string(p), "\n"))
+	if len(b.entries) > b.size {
+		b.entries = b.entries[len(b.entries)-b.size:]
+	}
+	return len(p), nil
+}
+
+// Entries returns a copy of the kept lines, oldest first
+func (b *logBuffer) Entries() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.entries...)
+}
+
+// rotatingFile appends to a log file, moving it aside to file.1 (and older
+// files to file.2 and so on) once it reaches its size limit
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64 // Zero never rotates
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and starts a new file
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.maxBackups > 0 {
+		for i := r.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
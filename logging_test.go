@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := range 4 {
+		fmt.Fprintf(f, "entry %d\n", i) // 8 bytes, so every entry starts a new file
+	}
+
+	for name, want := range map[string]string{
+		"app.log":   "entry 3\n",
+		"app.log.1": "entry 2\n",
+		"app.log.2": "entry 1\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than 2 backups")
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	defer func(l *slog.Logger) { logger = l }(logger)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	logs, closeLog, err := setupLogging(logOptions{file: path, level: "warn", format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+	logger.Warn("shown", "path", "/tmp/x")
+	closeLog()
+
+	entries := logs.Entries()
+	if len(entries) != 1 || !strings.Contains(entries[0], `msg=shown path=/tmp/x`) {
+		t.Errorf("log panel entries = %q", entries)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"msg":"shown","path":"/tmp/x"`) || strings.Contains(string(data), "hidden") {
+		t.Errorf("log file = %s", data)
+	}
+
+	if _, _, err := setupLogging(logOptions{level: "loud", format: "text"}); err == nil {
+		t.Error("accepted an unknown level")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// toggleLogPanel shows or hides the recent log entries over the panes
+func (m Model) toggleLogPanel() Model {
+	if m.logs == nil {
+		return m
+	}
+	m.showLogs = !m.showLogs
+	if m.showLogs {
+		m = m.refreshLogPanel()
+		m.logView.GotoBottom()
+	}
+	return m
+}
+
+// refreshLogPanel sizes the log panel to the terminal and loads the latest
+// entries, staying at the bottom when it was there
+func (m Model) refreshLogPanel() Model {
+	follow := m.logView.AtBottom()
+	width := max(1, m.width-BorderWidth)
+	m.logView.Width = width
+	m.logView.Height = max(1, m.height-HelpTextHeight-BorderWidth)
+
+	entries := m.logs.Entries()
+	if len(entries) == 0 {
+		entries = []string{"No log entries yet; run with -log-level debug for more detail"}
+	}
+	for i, entry := range entries {
+		entries[i] = ansi.Hardwrap(entry, width, true)
+	}
+	m.logView.SetContent(strings.Join(entries, "\n"))
+	if follow {
+		m.logView.GotoBottom()
+	}
+	return m
+}
+
+// handleLogPanel routes keys to the open log panel
+func (m Model) handleLogPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m, k, ok := m.readKey(msg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(k, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(k, m.keys.LogPanel, m.keys.PaneSelection):
+		return m.toggleLogPanel(), nil
+	}
+	m.keys.moveViewport(&m.logView, k)
+	return m, nil
+}
+
+// logPanelView renders the log panel in place of the panes
+func (m Model) logPanelView() string {
+	return m.theme.borderStyle(m.theme.FocusedBorder).
+		Width(m.logView.Width).
+		Height(m.logView.Height).
+		Render(m.logView.View())
+}
@@ -1,20 +1,24 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/muesli/reflow/wordwrap"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
 )
 
 // Layout constants - consolidating all magic numbers
@@ -43,18 +47,23 @@ type Layout struct {
 	TerminalHeight int
 
 	// Calculated dimensions
+	ParentPaneWidth int // Zero outside the Miller layout
 	LeftPaneWidth   int
 	LeftPaneHeight  int
 	RightPaneWidth  int
 	RightPaneHeight int
 
-	// Viewport dimensions (content area within right pane)
+	// Viewport dimensions (content area of the active split within right pane)
 	ViewportWidth  int
 	ViewportHeight int
 
+	// Dimensions of every content split, in order
+	Splits []SplitLayout
+
 	// List dimensions (content area within left pane)
-	ListWidth  int
-	ListHeight int
+	ListWidth       int
+	ListHeight      int
+	ParentListWidth int
 
 	// State-dependent flags
 	IsFullscreen     bool
@@ -64,46 +73,107 @@ type Layout struct {
 // CalculateLayout computes all layout dimensions based on current state
 func (m Model) CalculateLayout() Layout {
 	layout := Layout{
-		TerminalWidth:    m.width,
-		TerminalHeight:   m.height,
-		IsFullscreen:     m.isFullscreen,
-		HasContentHeader: m.currentFilePath != "",
+		TerminalWidth:  m.width,
+		TerminalHeight: m.height,
+		IsFullscreen:   m.isFullscreen,
+		Splits:         make([]SplitLayout, len(m.splits)),
+	}
+	for i, split := range m.splits {
+		layout.Splits[i].HasContentHeader = split.tab().path != ""
 	}
 
 	if layout.IsFullscreen {
-		// Fullscreen mode: use most of the terminal width
-		layout.ViewportWidth = layout.TerminalWidth - FullscreenBuffer
-
-		if layout.HasContentHeader {
-			layout.ViewportHeight = layout.TerminalHeight - HelpTextHeight - ContentHeaderHeight
+		// Fullscreen mode: only the active split is shown, using most of the terminal width
+		active := &layout.Splits[m.activeSplit]
+		active.Visible = true
+		active.Y = HelpTextHeight
+		active.PaneWidth = layout.TerminalWidth
+		active.ViewportWidth = layout.TerminalWidth - FullscreenBuffer
+
+		if active.HasContentHeader {
+			active.ViewportHeight = layout.TerminalHeight - HelpTextHeight - ContentHeaderHeight
 		} else {
-			layout.ViewportHeight = layout.TerminalHeight - HelpTextHeight
+			active.ViewportHeight = layout.TerminalHeight - HelpTextHeight
 		}
 	} else {
 		// Normal mode: split into left and right panes
 		availableHeight := layout.TerminalHeight - HelpTextHeight
 
+		// The Miller layout adds the parent directory column on the far left
+		parentPaneRenderedWidth := 0
+		if m.layoutMode == MillerLayout && !m.navigatorCollapsed {
+			parentPaneRenderedWidth = min(MaxParentPaneWidth, layout.TerminalWidth/6)
+			layout.ParentPaneWidth = parentPaneRenderedWidth - BorderWidth
+			layout.ParentListWidth = layout.ParentPaneWidth
+		}
+
 		// Calculate left pane dimensions
-		leftPaneRenderedWidth := min(MaxLeftPaneWidth, layout.TerminalWidth/4)
-		layout.LeftPaneWidth = leftPaneRenderedWidth - BorderWidth
+		leftPaneRenderedWidth := m.navigatorRenderedWidth(parentPaneRenderedWidth)
+		layout.LeftPaneWidth = max(0, leftPaneRenderedWidth-BorderWidth)
 		layout.LeftPaneHeight = availableHeight - BorderWidth // Subtract border height since lipgloss adds it
 		layout.ListWidth = layout.LeftPaneWidth
 		layout.ListHeight = layout.LeftPaneHeight - BorderWidth
 
 		// Calculate right pane dimensions
-		layout.RightPaneWidth = layout.TerminalWidth - leftPaneRenderedWidth - PaneSpacing - BorderWidth
+		layout.RightPaneWidth = layout.TerminalWidth - parentPaneRenderedWidth - leftPaneRenderedWidth - PaneSpacing - BorderWidth
 		layout.RightPaneHeight = availableHeight - BorderWidth // Subtract border height since lipgloss adds it
 
-		// Calculate viewport dimensions within right pane
-		layout.ViewportWidth = layout.RightPaneWidth
+		// Divide the right pane between the splits, giving any remainder to the last
+		renderedWidth := layout.RightPaneWidth + BorderWidth
+		renderedHeight := layout.RightPaneHeight + BorderWidth
+		offset := 0
+		for i := range layout.Splits {
+			split := &layout.Splits[i]
+			split.Visible = true
+			split.X = parentPaneRenderedWidth + leftPaneRenderedWidth
+			split.Y = HelpTextHeight
+			split.PaneWidth = layout.RightPaneWidth
+			split.PaneHeight = layout.RightPaneHeight
+
+			last := i == len(layout.Splits)-1
+			if m.splitDirection == SplitVertical {
+				width := renderedWidth / len(layout.Splits)
+				if last {
+					width = renderedWidth - offset
+				}
+				split.X += offset
+				split.PaneWidth = width - BorderWidth
+				offset += width
+			} else {
+				height := renderedHeight / len(layout.Splits)
+				if last {
+					height = renderedHeight - offset
+				}
+				split.Y += offset
+				split.PaneHeight = height - BorderWidth
+				offset += height
+			}
 
-		if layout.HasContentHeader {
-			layout.ViewportHeight = layout.RightPaneHeight - ContentHeaderHeight + 1 // Add 1 back because we already subtracted one for the border
-		} else {
-			layout.ViewportHeight = layout.RightPaneHeight - BorderWidth
+			// Calculate viewport dimensions within the split
+			split.ViewportWidth = split.PaneWidth
+
+			if split.HasContentHeader {
+				split.ViewportHeight = split.PaneHeight - ContentHeaderHeight + 1 // Add 1 back because we already subtracted one for the border
+			} else {
+				split.ViewportHeight = split.PaneHeight - BorderWidth
+			}
 		}
 	}
 
+	// The heading sidebar takes its room from the content
+	for i, split := range m.splits {
+		if s := &layout.Splits[i]; s.Visible && split.tab().showsOutline() {
+			s.OutlineWidth = outlineWidth(s.ViewportWidth)
+			s.ViewportWidth -= s.OutlineWidth
+		}
+	}
+
+	// The active split's viewport is the one most of the app works with
+	active := layout.Splits[m.activeSplit]
+	layout.ViewportWidth = active.ViewportWidth
+	layout.ViewportHeight = active.ViewportHeight
+	layout.HasContentHeader = active.HasContentHeader
+
 	return layout
 }
 
@@ -112,7 +182,7 @@ type Pane int
 
 const (
 	NavigatorPane Pane = iota
-	ContentPane
+	ContentPane        // The first content split; further splits follow in order
 )
 
 // Mode represents the current interaction mode
@@ -123,16 +193,44 @@ const (
 	PaneSelectionMode             // In pane selection mode (can switch between panes)
 )
 
+// ContentMode selects what the content pane shows for the current file
+type ContentMode int
+
+const (
+	FileContent    ContentMode = iota // The file itself, rendered or with line numbers
+	DiffContent                       // A diff against a revision or another file
+	BlameContent                      // The file annotated by git blame
+	HistoryContent                    // The commits touching the file
+)
+
 // FileItem represents a file in the navigator
 type FileItem struct {
-	name  string
-	path  string
-	isDir bool
+	name   string
+	path   string
+	isDir  bool
+	git    GitStatus
+	picked bool // Toggled in multi-pick mode
+	// bookmark is the letter of an entry in the bookmark list, and location the
+	// directory it is in, shown in place of its kind
+	bookmark string
+	location string
 }
 
 func (f FileItem) FilterValue() string { return f.name }
-func (f FileItem) Title() string       { return f.name }
+func (f FileItem) Title() string {
+	title := f.name
+	if badge := f.git.Badge(); badge != "" {
+		title += " " + badge
+	}
+	if f.picked {
+		title = "✓ " + title
+	}
+	return title
+}
 func (f FileItem) Description() string {
+	if f.location != "" {
+		return f.location
+	}
 	if f.isDir {
 		return "Directory"
 	}
@@ -142,7 +240,10 @@ func (f FileItem) Description() string {
 // Model holds the application state
 type Model struct {
 	list             list.Model
-	viewport         viewport.Model
+	splits           []*Split       // Content viewports, each with its own tabs, never empty
+	activeSplit      int            // Split that receives opened files, even while the navigator is focused
+	splitDirection   SplitDirection // Arranges every split, set by the last split key
+	scrollLock       bool           // Scroll every split together
 	mode             Mode
 	selectedPane     Pane
 	focusedPane      Pane
@@ -150,135 +251,351 @@ type Model struct {
 	height           int
 	currentDir       string
 	directoryHistory []string
-	fileContent      string
 	isFullscreen     bool
-	markdownRenderer *glamour.TermRenderer
-	showLineNumbers  bool
-	currentFilePath  string
+	showLineNumbers  bool   // Line number setting for newly opened tabs
 	layout           Layout // Consolidated layout calculations
+
+	wrapMode          WrapMode
+	listing           filebrowser.ListOptions
+	maxNavigatorWidth int // Widest navigator before it is resized, MaxLeftPaneWidth when zero
+	markdownWidth     int // Widest rendered markdown, zero to fit the pane
+
+	// layoutMode arranges the panes; the Miller layout adds parentList and previews the selection
+	layoutMode LayoutMode
+	parentList list.Model
+
+	// navigatorRatio is the navigator's share of the terminal width, zero for the default
+	navigatorRatio     float64
+	navigatorCollapsed bool
+	draggingDivider    bool
+	// statePath is where the pane arrangement is remembered, empty to not persist it
+	statePath string
+	// sessionPath is where the session is saved on quitting, empty to not save it
+	sessionPath string
+
+	// clipboard receives OSC 52 copy sequences: the terminal, or the SSH session
+	clipboard io.Writer
+	// clipboardTmux wraps the sequences for tmux, when the terminal or SSH session runs in it
+	clipboardTmux bool
+	// systemClipboard copies to the local desktop's clipboard first, when not serving over SSH
+	systemClipboard bool
+	// picker is set in picker mode, where choosing entries prints them and exits
+	picker *picker
+	// stdin is the content piped in when running as a pager, nil otherwise
+	stdin *pagerInput
+	// notice is a one-off message shown in place of the help text until the next input
+	notice string
+	// lastClickAt and lastClickIndex detect double clicks on list items
+	lastClickAt    time.Time
+	lastClickIndex int
+
+	// rootDir confines navigation to a subtree when set (used by the SSH server)
+	rootDir string
+	// theme colours every style, including the markdown and code highlighting
+	theme Theme
+	// audit records what a remote session looks at, nil when not serving over SSH
+	audit *log.Logger
+
+	// gitStatus is the latest snapshot for the repository around currentDir, nil outside one
+	gitStatus *GitRepoStatus
+	// gitStamp is the working tree fingerprint gitStatus was loaded at
+	gitStamp time.Time
+
+	// blameCache holds blames by file and revision, shared by every copy of the model
+	blameCache *blameCache
+	// renderCache holds rendered files and markdown renderers, shared by every copy of the model
+	renderCache *renderCache
+	// markedPath is a file marked in the navigator to diff against another
+	markedPath string
+
+	// keys maps key presses to actions; keyPrefix holds the start of a multi-key sequence
+	keys      KeyMap
+	keyPrefix string
+
+	// marks are the lines marked in each file by name; pendingMark is set while
+	// waiting for the key naming a mark. marks is shared by every copy of the model.
+	marks       map[string]map[string]int
+	pendingMark markOp
+	// jumps are positions to return to with JumpBack, and jumpIndex the one
+	// returned to, len(jumps) unless jumping back
+	jumps     []jumpPosition
+	jumpIndex int
+	// places are the bookmarks and frecency of visited paths, shared by every
+	// copy of the model; bookmarkList is set while the navigator lists the bookmarks
+	places       *places
+	bookmarkList bool
+
+	// prompt is a single-line input shown in place of the help text while promptSubmit is set
+	prompt       textinput.Model
+	promptSubmit promptSubmitFunc
+
+	// navigatorLoad and parentLoad track directory listings in flight; once the
+	// navigator is listed, selectOnListing is selected, or the first entry when empty
+	navigatorLoad   loadRequest
+	parentLoad      loadRequest
+	selectOnListing string
+	// navigatorErr is why currentDir could not be listed
+	navigatorErr error
+	// spinner turns in the headers of panes that are loading
+	spinner  spinner.Model
+	spinning bool
+
+	// logs holds recent log entries, nil where the log panel is unavailable (SSH sessions)
+	logs *logBuffer
+	// logView shows the entries in place of the panes while showLogs is set
+	logView  viewport.Model
+	showLogs bool
 }
 
-// Initialize the model
-func initialModel() Model {
-	// Get current directory
-	currentDir, err := os.Getwd()
+// Initialize the model from the user's settings, opening files in tabs and
+// showing stdin as well when it is not nil
+func initialModel(cfg settings, files []string, stdin io.Reader) (Model, error) {
+	if cfg.cfg.Open != "" {
+		files = append([]string{cfg.cfg.Open}, files...)
+	}
+
+	// Start in the configured directory, or the one holding the first file to open
+	currentDir := cfg.cfg.StartDir
+	if currentDir == "" && len(files) > 0 {
+		currentDir = filepath.Dir(files[0])
+	}
+	if currentDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			wd = "."
+		}
+		currentDir = wd
+	}
+	currentDir, err := filepath.Abs(currentDir)
 	if err != nil {
-		currentDir = "."
+		return Model{}, err
+	}
+	if info, err := os.Stat(currentDir); err != nil || !info.IsDir() {
+		return Model{}, fmt.Errorf("%q is not a directory", currentDir)
 	}
 
+	// Only an automatic theme needs to ask the terminal for its background
+	darkBackground := cfg.cfg.Theme != "auto" || lipgloss.HasDarkBackground()
+	m := cfg.applyTo(newModel(currentDir, ""), darkBackground)
+	m.clipboard = os.Stdout
+	m.clipboardTmux = os.Getenv("TMUX") != ""
+	m.statePath = defaultStatePath()
+	if path := defaultPlacesPath(); path != "" {
+		m.places = loadPlaces(path)
+	}
+	if m.statePath != "" {
+		state := loadState(m.statePath)
+		m.navigatorRatio = state.NavigatorRatio
+		m.navigatorCollapsed = state.NavigatorCollapsed
+		if m.navigatorCollapsed {
+			m = m.focusSplit(0)
+		}
+	}
+
+	// Sessions restore what was shown when nothing else is asked for
+	if stdin == nil && (cfg.cfg.RestoreSession || cfg.cfg.Session != "") {
+		m.sessionPath = sessionPath(cfg.cfg.Session, currentDir)
+		if s, ok := loadSession(m.sessionPath); ok && len(files) == 0 && m.sessionPath != "" {
+			// A named session moves to its directory unless one was given
+			m = m.restoreSession(s, cfg.cfg.Session == "" || cfg.cfg.StartDir == "")
+			return m, nil
+		}
+	}
+
+	if stdin != nil {
+		m = m.openStdin(stdin)
+	}
+	for i, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return Model{}, err
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return Model{}, fmt.Errorf("%q is not a file", file)
+		}
+		if i == 0 {
+			for i, item := range m.list.Items() {
+				if item.(FileItem).path == path {
+					m.list.Select(i)
+				}
+			}
+		}
+		m = m.openTab(path).focusSplit(m.activeSplit)
+	}
+	m.split().activeTab = 0 // Show stdin or the first file
+	return m, nil
+}
+
+// newModel creates a model starting in dir, confined to root when root is not empty
+func newModel(dir, root string) Model {
+	currentDir := dir
+
 	// Create file list
-	files := getFileList(currentDir)
+	entries, _ := filebrowser.ReadDir(currentDir, root, filebrowser.ListOptions{})
+	files := fileItems(entries)
 
 	// Setup list
-	l := list.New(files, list.NewDefaultDelegate(), 0, 0)
-	l.Title = formatDirectoryPath(currentDir)
+	l := list.New(files, newFileDelegate(DarkTheme), 0, 0)
+	DarkTheme.styleList(&l)
+	l.Title = filebrowser.DisplayPath(currentDir, root)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.SetShowHelp(false)
 
-	// Setup viewport
-	vp := viewport.New(0, 0)
-	vp.SetContent("Select a file to view its content")
-
-	// Initialize markdown renderer
-	markdownRenderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(100), // Fixed reasonable width
-	)
-	if err != nil {
-		markdownRenderer = nil // Fall back to no rendering if creation fails
-	}
-
 	return Model{
 		list:             l,
-		viewport:         vp,
+		parentList:       newParentList(DarkTheme),
+		splits:           []*Split{newSplit(newTab(true))},
+		activeSplit:      0,
 		mode:             NavigatorMode,
 		selectedPane:     NavigatorPane,
 		focusedPane:      NavigatorPane,
 		currentDir:       currentDir,
 		directoryHistory: []string{},
-		fileContent:      "",
 		isFullscreen:     false,
-		markdownRenderer: markdownRenderer,
+		renderCache:      newRenderCache(DefaultRenderCacheMB << 20),
 		showLineNumbers:  true,
-		currentFilePath:  "",
 		layout:           Layout{}, // Layout will be calculated on first window resize
+		rootDir:          root,
+		blameCache:       newBlameCache(),
+		marks:            make(map[string]map[string]int),
+		places:           newPlaces(""), // Kept for the session unless loaded from disk
+		keys:             DefaultKeyMap(),
+		theme:            DarkTheme,
+		spinner:          spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 	}
 }
 
-// formatDirectoryPath formats a directory path with ~ substitution for home directory
-func formatDirectoryPath(path string) string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return path
+// fileItems turns directory entries into navigator items
+func fileItems(entries []filebrowser.Entry) []list.Item {
+	var items []list.Item
+	for _, entry := range entries {
+		items = append(items, FileItem{
+			name:  entry.Name,
+			path:  entry.Path,
+			isDir: entry.IsDir,
+		})
 	}
+	return items
+}
 
-	if strings.HasPrefix(path, homeDir) {
-		return strings.Replace(path, homeDir, "~", 1)
+// paneBorderColor returns the border colour for a pane: selected while choosing
+// a pane, focused while it has the keyboard, unfocused otherwise
+func (m Model) paneBorderColor(pane Pane) lipgloss.Color {
+	if m.mode == PaneSelectionMode && m.selectedPane == pane {
+		return m.theme.SelectedBorder
+	} else if m.mode == NavigatorMode && m.focusedPane == pane {
+		return m.theme.FocusedBorder
 	}
+	return m.theme.UnfocusedBorder
+}
 
-	return path
+func (m Model) Init() tea.Cmd {
+	cmds := []tea.Cmd{loadGitStatusCmd(m.currentDir), gitTick()}
+	if m.stdin != nil {
+		cmds = append(cmds, readStdinCmd(m.stdin.reader))
+	}
+	return tea.Batch(cmds...)
 }
 
-// Get list of files in directory
-func getFileList(dir string) []list.Item {
-	var items []list.Item
+// navigatorTitle returns the list title: the current directory plus the git
+// branch, if any, and a spinner while listing
+func (m Model) navigatorTitle() string {
+	title := filebrowser.DisplayPath(m.currentDir, m.rootDir)
+	if m.gitStatus != nil {
+		title += "  " + m.gitStatus.Title()
+	}
+	if m.navigatorLoad.active() {
+		title += " " + m.spinner.View()
+	}
+	return title
+}
 
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return items
+// refreshNavigator lists currentDir in the background, reusing the git status
+// snapshot when still inside the same repository and loading a new one otherwise.
+// The old entries are cleared so they cannot be acted on meanwhile.
+func (m Model) refreshNavigator() (Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	if m.gitStatus == nil || !m.gitStatus.Contains(m.currentDir) {
+		m.gitStatus = nil
+		cmds = append(cmds, loadGitStatusCmd(m.currentDir))
 	}
 
-	// Add parent directory if not root
-	if dir != "/" {
-		items = append(items, FileItem{
-			name:  "..",
-			path:  filepath.Dir(dir),
-			isDir: true,
-		})
+	m.list.SetItems(nil)
+	m.navigatorErr = nil
+	m.bookmarkList = false
+	m, cmd := m.withSpinner(m.listDirCmd(&m.navigatorLoad, m.currentDir))
+	m.list.Title = m.navigatorTitle()
+	cmds = append(cmds, cmd)
+	if m.layoutMode == MillerLayout {
+		m, cmd = m.refreshParentList()
+		cmds = append(cmds, cmd)
 	}
+	return m, tea.Batch(cmds...)
+}
 
-	// Sort entries: directories first, then files
-	sort.Slice(entries, func(i, j int) bool {
-		if entries[i].IsDir() != entries[j].IsDir() {
-			return entries[i].IsDir()
-		}
-		return entries[i].Name() < entries[j].Name()
-	})
+// listNow lists the navigator, and the parent column in the Miller layout,
+// without waiting for the program to run, for setting up the model
+func (m Model) listNow() Model {
+	m = m.showListing(filebrowser.ReadDir(m.currentDir, m.rootDir, m.listing))
+	if parent := m.parentDir(); parent != "" && m.layoutMode == MillerLayout {
+		entries, _ := filebrowser.ReadDir(parent, m.rootDir, m.listing)
+		m = m.showParentListing(parent, entries)
+	}
+	return m
+}
 
-	for _, entry := range entries {
-		// Skip hidden files
-		if strings.HasPrefix(entry.Name(), ".") {
-			continue
+// showListing fills the navigator with the entries of currentDir, or the
+// reason they could not be listed
+func (m Model) showListing(entries []filebrowser.Entry, err error) Model {
+	m.navigatorErr = err
+	items := m.markPicked(applyGitStatus(fileItems(entries), m.gitStatus))
+	m.list.SetItems(items)
+	m.list.Title = m.navigatorTitle()
+	m.list.Select(0)
+	for i, item := range items {
+		if item.(FileItem).path == m.selectOnListing {
+			m.list.Select(i)
+			break
 		}
-
-		items = append(items, FileItem{
-			name:  entry.Name(),
-			path:  filepath.Join(dir, entry.Name()),
-			isDir: entry.IsDir(),
-		})
 	}
-
-	return items
+	m.selectOnListing = ""
+	return m
 }
 
-// Styles for the application
-var (
-	focusedBorderStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("42")) // Green
-
-	unfocusedBorderStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("240")) // White/Gray
-
-	selectedBorderStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("51")) // Cyan
-)
+// handleDirListed shows a listing that arrived, unless a newer one superseded it
+func (m Model) handleDirListed(msg dirListedMsg) (tea.Model, tea.Cmd) {
+	switch msg.id {
+	case m.navigatorLoad.id:
+		m.navigatorLoad.end()
+		if m.bookmarkList {
+			return m, nil // Listed before the bookmarks were shown
+		}
+		if msg.err != nil {
+			logger.Warn("listing failed", "dir", msg.dir, "err", msg.err)
+		}
+		m = m.showListing(msg.entries, msg.err)
+		return m.updatePreview()
+	case m.parentLoad.id:
+		m.parentLoad.end()
+		return m.showParentListing(msg.dir, msg.entries), nil
+	}
+	return m, nil
+}
 
-func (m Model) Init() tea.Cmd {
-	return nil
+// navigatorView renders the directory listing, or why there is none to show
+func (m Model) navigatorView() string {
+	var body string
+	switch {
+	case m.navigatorErr != nil:
+		body = m.loadErrorView(m.navigatorErr, m.currentDir, m.layout.ListWidth)
+	case m.navigatorLoad.active() && len(m.list.Items()) == 0:
+		body = "Loading…"
+	default:
+		return m.list.View()
+	}
+	title := m.list.Styles.TitleBar.Render(m.list.Styles.Title.Render(m.list.Title))
+	return lipgloss.NewStyle().MaxWidth(m.layout.ListWidth).Render(title + "\n\n" + body)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -287,30 +604,103 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
-		// Calculate layout dimensions using consolidated system
-		m.layout = m.CalculateLayout()
+		// Calculate layout dimensions and size the lists to match
+		m = m.applyLayout()
 
-		// Update list size
-		m.list.SetWidth(m.layout.ListWidth)
-		m.list.SetHeight(m.layout.ListHeight)
+		logger.Debug("layout calculated",
+			"terminal_width", m.layout.TerminalWidth, "terminal_height", m.layout.TerminalHeight,
+			"viewport_width", m.layout.ViewportWidth, "viewport_height", m.layout.ViewportHeight,
+			"fullscreen", m.layout.IsFullscreen)
+		if m.showLogs {
+			m = m.refreshLogPanel()
+		}
 
-		// Update viewport size
-		m.viewport.Width = m.layout.ViewportWidth
-		m.viewport.Height = m.layout.ViewportHeight
+		// Resize every split and re-render its content since the viewport width changed
+		if m.isFullscreen {
+			m = m.resizeActiveTab()
+			return m.rerenderCurrentFile()
+		}
+		updated, cmd := m.rerenderAllSplits()
+		m, previewCmd := updated.(Model).updatePreview()
+		return m, tea.Batch(cmd, previewCmd)
+
+	case stdinMsg:
+		return m.handleStdin(msg)
+	case stdinRenderMsg:
+		if m.stdin.done {
+			return m, nil // Already shown in full
+		}
+		return m.renderStdin()
+	case fileLoadedMsg:
+		return m.handleFileLoaded(msg)
+	case gitLoadedMsg:
+		return m.handleGitLoaded(msg)
+	case dirListedMsg:
+		return m.handleDirListed(msg)
+	case spinner.TickMsg:
+		// Stop turning once nothing is loading
+		if !m.busy() {
+			m.spinning = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		m.list.Title = m.navigatorTitle()
+		return m, cmd
+	case gitStatusMsg:
+		// Drop results for a directory we have already left
+		if msg.dir != m.currentDir {
+			return m, nil
+		}
+		m.gitStatus = msg.status
+		m.gitStamp = msg.stamp
+		m.list.SetItems(applyGitStatus(m.list.Items(), m.gitStatus))
+		m.list.Title = m.navigatorTitle()
+		m.parentList.SetItems(applyGitStatus(m.parentList.Items(), m.gitStatus))
+		return m, nil
 
-		debugLog("Layout calculated - Terminal: %dx%d, Viewport: %dx%d, Fullscreen: %v",
-			m.layout.TerminalWidth, m.layout.TerminalHeight,
-			m.layout.ViewportWidth, m.layout.ViewportHeight,
-			m.layout.IsFullscreen)
+	case blameMsg:
+		m.blameCache.put(msg.key, msg.result)
+		for _, split := range m.splits {
+			if t := split.tab(); t.mode == BlameContent && msg.key == blameKey(t.path, t.revision) {
+				return m.rerenderAllSplits()
+			}
+		}
+		return m, nil
 
-		// Re-render current file content if viewport width changed
-		if m.currentFilePath != "" {
-			return m.rerenderCurrentFile()
+	case gitTickMsg:
+		// Pick up entries logged since the panel was last refreshed
+		if m.showLogs {
+			m = m.refreshLogPanel()
+		}
+		gitDir := ""
+		if m.gitStatus != nil {
+			gitDir = m.gitStatus.GitDir
 		}
+		return m, tea.Batch(gitTick(), gitFingerprintCmd(m.currentDir, gitDir))
 
+	case gitFingerprintMsg:
+		if msg.dir == m.currentDir && m.gitStatus != nil && msg.stamp.After(m.gitStamp) {
+			m.gitStamp = msg.stamp // Avoid queueing duplicate loads while this one runs
+			return m, loadGitStatusCmd(m.currentDir)
+		}
 		return m, nil
 
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
 	case tea.KeyMsg:
+		m.notice = ""
+		if m.promptActive() {
+			return m.handlePrompt(msg)
+		}
+		if m.showLogs {
+			return m.handleLogPanel(msg)
+		}
+		if m.pendingMark != markNone {
+			return m.handleMarkName(msg)
+		}
+
 		switch m.mode {
 		case NavigatorMode:
 			return m.handleNavigatorMode(msg)
@@ -323,20 +713,38 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleNavigatorMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", "ctrl+c":
+	m, k, ok := m.readKey(msg)
+	if !ok {
+		return m, nil
+	}
+
+	if updated, cmd, ok := m.handleBookmarkList(k); ok {
+		return updated, cmd
+	}
+	if updated, cmd, ok := m.handlePick(k); ok {
+		return updated, cmd
+	}
+	if updated, cmd, ok := m.handleSelectionKey(k); ok {
+		return updated, cmd
+	}
+	if updated, cmd, ok := m.handleOutlineKey(k); ok {
+		return updated, cmd
+	}
+
+	switch {
+	case key.Matches(k, m.keys.Quit):
 		return m, tea.Quit
-	case "esc":
+	case key.Matches(k, m.keys.PaneSelection):
 		if m.isFullscreen {
 			// Exit fullscreen mode
 			m.isFullscreen = false
 			// Recalculate layout for normal mode
 			m.layout = m.CalculateLayout()
-			m.viewport.Width = m.layout.ViewportWidth
-			m.viewport.Height = m.layout.ViewportHeight
-			debugLog("ESC exiting fullscreen - Setting viewport width to: %d", m.viewport.Width)
+			m.tab().viewport.Width = m.layout.ViewportWidth
+			m.tab().viewport.Height = m.layout.ViewportHeight
+			logger.Debug("leaving fullscreen", "viewport_width", m.tab().viewport.Width)
 			// Re-render the current file content with the new viewport width
-			if m.currentFilePath != "" {
+			if m.tab().path != "" {
 				return m.rerenderCurrentFile()
 			}
 			return m, nil
@@ -345,77 +753,324 @@ func (m Model) handleNavigatorMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = PaneSelectionMode
 		m.selectedPane = m.focusedPane
 		return m, nil
-	case "left":
-		// Switch focus to navigator pane when on content pane (only if wrapping is enabled)
-		if m.focusedPane == ContentPane {
+	case key.Matches(k, m.keys.Left, m.keys.Right):
+		// Scroll unwrapped content sideways, back to the navigator from its left edge
+		if m.focusedPane.IsContent() && m.scrollsSideways() {
+			switch {
+			case key.Matches(k, m.keys.Right):
+				return m.scrollHorizontally(horizontalStep)
+			case m.tab().xOffset > 0:
+				return m.scrollHorizontally(-horizontalStep)
+			}
+		}
+		if key.Matches(k, m.keys.Left) && m.focusedPane.IsContent() {
 			m.focusedPane = NavigatorPane
+			if m.navigatorCollapsed {
+				return m.setNavigatorCollapsed(false).relayoutAndSave()
+			}
+			return m, nil
+		}
+		if updated, cmd, ok := m.handleHierarchyNavigation(k); ok {
+			m, previewCmd := updated.(Model).updatePreview()
+			return m, tea.Batch(cmd, previewCmd)
+		}
+		// Otherwise they page the navigator, as the list's own keys did
+		if m.focusedPane == NavigatorPane {
+			if key.Matches(k, m.keys.Left) {
+				m.list.PrevPage()
+			} else {
+				m.list.NextPage()
+			}
+		}
+		return m, nil
+	case key.Matches(k, m.keys.ShrinkNavigator, m.keys.GrowNavigator):
+		// Move the divider between the navigator and the content
+		delta := ResizeStep
+		if key.Matches(k, m.keys.ShrinkNavigator) {
+			delta = -delta
+		}
+		return m.resizeNavigator(delta).relayoutAndSave()
+	case key.Matches(k, m.keys.ToggleNavigator):
+		// Hide or show the navigator
+		if m.isFullscreen {
 			return m, nil
 		}
-	case "l":
+		return m.setNavigatorCollapsed(!m.navigatorCollapsed).relayoutAndSave()
+	case key.Matches(k, m.keys.LogPanel):
+		return m.toggleLogPanel(), nil
+	case key.Matches(k, m.keys.ToggleLayout):
+		// Switch between the two-pane and Miller layouts
+		if !m.isFullscreen {
+			return m.toggleLayoutMode()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.ToggleLineNumbers):
 		// Toggle line numbers when focused on content pane
-		if m.focusedPane == ContentPane {
-			m.showLineNumbers = !m.showLineNumbers
+		if m.focusedPane.IsContent() {
+			m.tab().showLineNumbers = !m.tab().showLineNumbers
 			return m.rerenderCurrentFile()
 		}
 		return m, nil
-	case "f":
+	case key.Matches(k, m.keys.ToggleWrap):
+		if m.focusedPane.IsContent() {
+			return m.toggleWrap()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.Fullscreen):
 		// Toggle fullscreen only for content pane when focused
-		if m.focusedPane == ContentPane {
+		if m.focusedPane.IsContent() {
 			m.isFullscreen = !m.isFullscreen
-			debugLog("Fullscreen toggled to: %v", m.isFullscreen)
 			// Recalculate layout for new fullscreen state
 			m.layout = m.CalculateLayout()
-			m.viewport.Width = m.layout.ViewportWidth
-			m.viewport.Height = m.layout.ViewportHeight
-			debugLog("Fullscreen toggle - Setting viewport width to: %d", m.viewport.Width)
+			m.tab().viewport.Width = m.layout.ViewportWidth
+			m.tab().viewport.Height = m.layout.ViewportHeight
+			logger.Debug("fullscreen toggled", "fullscreen", m.isFullscreen, "viewport_width", m.tab().viewport.Width)
 			// Re-render the current file content with the new viewport width
-			if m.currentFilePath != "" {
+			if m.tab().path != "" {
 				return m.rerenderCurrentFile()
 			}
 		}
 		return m, nil
-	case "z":
+	case key.Matches(k, m.keys.Back):
 		if m.focusedPane == NavigatorPane {
 			return m.goToPreviousDirectory()
 		}
 		return m, nil
-	case "enter":
+	case key.Matches(k, m.keys.NextTab, m.keys.PrevTab):
+		// Cycle through the tabs of the active split
+		if len(m.split().tabs) > 1 {
+			if key.Matches(k, m.keys.NextTab) {
+				return m.switchTab(m.split().activeTab + 1)
+			}
+			return m.switchTab(m.split().activeTab - 1)
+		}
+		return m, nil
+	case key.Matches(k, m.keys.NextPane):
+		// Cycle focus through the navigator and every split
+		if !m.isFullscreen {
+			m = m.focusPane(m.nextPane(m.focusedPane, 1))
+		}
+		return m, nil
+	case key.Matches(k, m.keys.SplitVertical, m.keys.SplitHorizontal):
+		// Split the content area, arranging every split side by side or stacked
+		if m.focusedPane.IsContent() && !m.isFullscreen && m.tab().path != "" {
+			if key.Matches(k, m.keys.SplitVertical) {
+				return m.addSplit(SplitVertical)
+			}
+			return m.addSplit(SplitHorizontal)
+		}
+		return m, nil
+	case key.Matches(k, m.keys.CloseSplit):
+		if m.focusedPane.IsContent() && !m.isFullscreen {
+			return m.closeSplit()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.ScrollLock):
+		// Lock scrolling between splits for comparing similar files
+		if len(m.splits) > 1 {
+			m.scrollLock = !m.scrollLock
+		}
+		return m, nil
+	case key.Matches(k, m.keys.CloseTab):
+		if m.focusedPane.IsContent() && m.tab().path != "" {
+			return m.closeTab()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.GoToLine) && m.focusedPane.IsContent():
+		if m.tab().path != "" && m.tab().mode == FileContent {
+			return m.promptGoToLine()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.SetMark, m.keys.JumpToMark) && m.focusedPane.IsContent():
+		// Wait for the letter naming the mark
+		if m.tab().path != "" && m.tab().mode == FileContent {
+			m.pendingMark = markJump
+			if key.Matches(k, m.keys.SetMark) {
+				m.pendingMark = markSet
+			}
+		}
+		return m, nil
+	case key.Matches(k, m.keys.Visual, m.keys.VisualLine):
+		if m.focusedPane.IsContent() && m.tab().path != "" && m.tab().mode != HistoryContent {
+			return m.startSelection(key.Matches(k, m.keys.VisualLine)), nil
+		}
+		return m, nil
+	case key.Matches(k, m.keys.ToggleOutline):
+		if m.focusedPane.IsContent() {
+			return m.toggleOutline()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.NextLink, m.keys.PrevLink):
+		if m.focusedPane.IsContent() && m.rendersMarkdown() {
+			return m.cycleLink(key.Matches(k, m.keys.NextLink)), nil
+		}
+		return m, nil
+	case key.Matches(k, m.keys.ToggleRaw):
+		if m.focusedPane.IsContent() {
+			return m.toggleRaw()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.CopyLocation):
+		return m.copyLocation()
+	case key.Matches(k, m.keys.JumpBack):
+		return m.jumpBack()
+	case key.Matches(k, m.keys.JumpForward):
+		return m.jumpForward()
+	case key.Matches(k, m.keys.Bookmark) && m.focusedPane == NavigatorPane:
+		// Wait for the letter naming the bookmark
+		if _, ok := m.list.SelectedItem().(FileItem); ok && !m.bookmarkList {
+			m.pendingMark = markBookmark
+		}
+		return m, nil
+	case key.Matches(k, m.keys.JumpToBookmark):
+		if len(m.places.Bookmarks) == 0 {
+			m.notice = "No bookmarks yet"
+			return m, nil
+		}
+		m.pendingMark = markGoBookmark
+		return m, nil
+	case key.Matches(k, m.keys.Bookmarks):
+		return m.showBookmarks()
+	case key.Matches(k, m.keys.JumpPrompt):
+		return m.promptJump()
+	case key.Matches(k, m.keys.Mark):
+		// Mark the selected file as the old side of a file-to-file diff
+		if m.focusedPane == NavigatorPane {
+			if item, ok := m.list.SelectedItem().(FileItem); ok && !item.isDir {
+				if m.markedPath == item.path {
+					m.markedPath = ""
+				} else {
+					m.markedPath = item.path
+				}
+			}
+		}
+		return m, nil
+	case key.Matches(k, m.keys.Diff):
+		if m.focusedPane == NavigatorPane {
+			return m.diffMarkedFile()
+		}
+		if m.tab().path == "" || m.tab().path == StdinPath {
+			return m, nil
+		}
+		if m.tab().mode == DiffContent {
+			return m.setContentMode(FileContent)
+		}
+		return m.showRevisionDiff("HEAD")
+	case key.Matches(k, m.keys.Blame):
+		// Toggle blame for the file (at the revision being viewed, if any)
+		if !m.focusedPane.IsContent() || m.tab().path == "" || m.tab().path == StdinPath {
+			return m, nil
+		}
+		if m.tab().mode == BlameContent {
+			return m.setContentMode(FileContent)
+		}
+		m.auditf("blame %s", filebrowser.DisplayPath(m.tab().path, m.rootDir))
+		var load tea.Cmd
+		if _, ok := m.blameCache.get(blameKey(m.tab().path, m.tab().revision)); !ok {
+			load = loadBlameCmd(m.tab().path, m.tab().revision, m.tab().revisionPath)
+		}
+		updated, cmd := m.setContentMode(BlameContent)
+		return updated, tea.Batch(cmd, load)
+	case key.Matches(k, m.keys.History):
+		// Toggle the commit history of the file
+		if !m.focusedPane.IsContent() || m.tab().path == "" || m.tab().path == StdinPath {
+			return m, nil
+		}
+		if m.tab().mode == HistoryContent {
+			m.tab().revision = ""
+			return m.setContentMode(FileContent)
+		}
+		return m.showHistory()
+	case key.Matches(k, m.keys.Patch):
+		// Show the patch of the selected commit
+		if m.focusedPane.IsContent() && m.tab().mode == HistoryContent {
+			return m.showSelectedCommit(true)
+		}
+		return m, nil
+	case key.Matches(k, m.keys.DiffRevision):
+		if m.focusedPane.IsContent() && m.tab().path != "" && m.tab().path != StdinPath {
+			return m.openPrompt("Diff against revision:", "HEAD", func(m Model, revision string) (tea.Model, tea.Cmd) {
+				return m.showRevisionDiff(strings.TrimSpace(revision))
+			})
+		}
+		return m, nil
+	case key.Matches(k, m.keys.SideBySide):
+		if m.focusedPane.IsContent() && m.tab().mode == DiffContent {
+			m.tab().diff.sideBySide = !m.tab().diff.sideBySide
+			return m.rerenderCurrentFile()
+		}
+		return m, nil
+	case key.Matches(k, m.keys.NextHunk, m.keys.PrevHunk):
+		// Jump between hunks of a diff
+		if m.focusedPane.IsContent() && m.tab().mode == DiffContent {
+			offset := m.tab().diff.nextHunk(m.tab().viewport.YOffset)
+			if key.Matches(k, m.keys.PrevHunk) {
+				offset = m.tab().diff.previousHunk(m.tab().viewport.YOffset)
+			}
+			if offset >= 0 {
+				m.tab().viewport.SetYOffset(offset)
+			}
+		}
+		return m, nil
+	case key.Matches(k, m.keys.Open):
 		if m.focusedPane == NavigatorPane {
 			return m.handleFileSelection()
 		}
+		if m.tab().mode == HistoryContent {
+			return m.showSelectedCommit(false)
+		}
+		if m.tab().focusedLink > 0 {
+			return m.followLink()
+		}
 		return m, nil
 	}
 
-	// Handle navigation based on focused pane
-	switch m.focusedPane {
-	case NavigatorPane:
-		var cmd tea.Cmd
-		m.list, cmd = m.list.Update(msg)
-		return m, cmd
-	case ContentPane:
-		var cmd tea.Cmd
-		m.viewport, cmd = m.viewport.Update(msg)
-		return m, cmd
+	// Move within the focused pane
+	return m.handleMovement(k)
+}
+
+// handleMovement moves the cursor of the focused list or scrolls the focused viewport
+func (m Model) handleMovement(k keyPress) (Model, tea.Cmd) {
+	if m.focusedPane == NavigatorPane {
+		m.keys.moveList(&m.list, k)
+		return m.updatePreview()
+	}
+	if m.tab().mode == HistoryContent {
+		m.keys.moveList(&m.tab().history, k)
+		return m, nil
 	}
 
-	return m, nil
+	vp := &m.tab().viewport
+	offset := vp.YOffset
+	m.keys.moveViewport(vp, k)
+	return m.syncScroll(m.activeSplit, vp.YOffset-offset), nil
 }
 
 func (m Model) handlePaneSelectionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", "ctrl+c":
+	m, k, ok := m.readKey(msg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(k, m.keys.Quit):
 		return m, tea.Quit
-	case "esc":
+	case key.Matches(k, m.keys.PaneSelection):
 		m.mode = NavigatorMode
 		return m, nil
-	case "left":
-		m.selectedPane = NavigatorPane
+	case key.Matches(k, m.keys.Left, m.keys.Up):
+		// Step towards the navigator, through every split
+		first := NavigatorPane
+		if m.navigatorCollapsed {
+			first = ContentPane
+		}
+		m.selectedPane = Pane(max(int(first), int(m.selectedPane)-1))
 		return m, nil
-	case "right":
-		m.selectedPane = ContentPane
+	case key.Matches(k, m.keys.Right, m.keys.Down):
+		m.selectedPane = Pane(min(int(splitPane(len(m.splits)-1)), int(m.selectedPane)+1))
 		return m, nil
-	case "enter":
-		m.focusedPane = m.selectedPane
+	case key.Matches(k, m.keys.Open):
+		m = m.focusPane(m.selectedPane)
 		m.mode = NavigatorMode
 		return m, nil
 	}
@@ -429,73 +1084,100 @@ func (m Model) goToPreviousDirectory() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	m = m.recordJump()
+
 	// Pop the last directory from history
 	prevDir := m.directoryHistory[len(m.directoryHistory)-1]
 	m.directoryHistory = m.directoryHistory[:len(m.directoryHistory)-1]
 
 	// Navigate to the previous directory
 	m.currentDir = prevDir
-	files := getFileList(m.currentDir)
-	m.list.SetItems(files)
-	m.list.Title = formatDirectoryPath(m.currentDir)
-	m.list.Select(0)
-	m.viewport.SetContent("Select a file to view its content")
-	m.fileContent = ""
-	m.currentFilePath = ""
-
-	// Recalculate layout since we cleared the current file (affects header display)
-	m.layout = m.CalculateLayout()
-	m.viewport.Width = m.layout.ViewportWidth
-	m.viewport.Height = m.layout.ViewportHeight
-
-	return m, nil
+	m, cmd := m.refreshNavigator()
+	return m, tea.Batch(cmd, m.recordVisit(prevDir, true))
 }
 
 func (m Model) rerenderCurrentFile() (tea.Model, tea.Cmd) {
-	if m.currentFilePath == "" {
+	if m.tab().path == "" {
 		return m, nil
 	}
 
-	// Read file content
-	content, err := os.ReadFile(m.currentFilePath)
-	if err != nil {
-		m.fileContent = fmt.Sprintf("Error reading file: %v", err)
-	} else {
-		rawContent := string(content)
-		filename := filepath.Base(m.currentFilePath)
-
-		debugLog("rerenderCurrentFile - Viewport width: %d, Fullscreen: %v", m.layout.ViewportWidth, m.layout.IsFullscreen)
-
-		if isMarkdownFile(filename) {
-			// Render markdown with Glamour (no line numbers, no manual wrapping)
-			m.fileContent = m.renderMarkdown(rawContent)
-		} else {
-			// Step 1: Add line numbers if enabled, otherwise use raw content
-			var contentWithLineNumbers string
-			if m.showLineNumbers {
-				contentWithLineNumbers = addLineNumbers(rawContent)
-			} else {
-				contentWithLineNumbers = rawContent
-			}
+	switch m.tab().mode {
+	case DiffContent:
+		m.tab().content = m.tab().diff.render(m.layout.ViewportWidth, m.theme)
+		m.tab().viewport.SetContent(m.tab().content)
+		return m, nil
+	case BlameContent:
+		result, ok := m.blameCache.get(blameKey(m.tab().path, m.tab().revision))
+		switch {
+		case !ok:
+			m.tab().content = "Running git blame…"
+		case result.err != nil:
+			m.tab().content = fmt.Sprintf("Error running git blame: %v", result.err)
+		default:
+			m.tab().content = renderBlame(result.lines, m.layout.ViewportWidth, time.Now(), m.theme)
+		}
+		m.tab().viewport.SetContent(m.tab().content)
+		return m, nil
+	case HistoryContent:
+		m.tab().history.SetSize(m.layout.ViewportWidth, m.layout.ViewportHeight)
+		return m, nil
+	}
 
-			// Step 2: Wrap the final content
-			wrapWidth := m.layout.ViewportWidth
-			if wrapWidth > 0 {
-				m.fileContent = wordwrap.String(contentWithLineNumbers, wrapWidth)
-			} else {
-				m.fileContent = contentWithLineNumbers
-			}
+	// Read the file off the UI goroutine, showing what was read before meanwhile
+	var cmd tea.Cmd
+	if m.tab().needsLoad() {
+		m, cmd = m.withSpinner(m.loadTabCmd(m.tab()))
+	}
+	switch {
+	case m.tab().path != StdinPath && m.tab().loadedAs != m.tab().source():
+		m.tab().content = "Loading…"
+	case m.tab().loadErr != nil:
+		m.tab().content = m.loadErrorView(m.tab().loadErr, m.tab().path, m.layout.ViewportWidth)
+	case m.tab().isDir:
+		m.tab().content = renderDirectoryPreview(m.tab().entries)
+	default:
+		m.tab().content = m.renderFileCached()
+		if m.scrollsSideways() {
+			// Keep the gutter in place and stop once the longest line is in view
+			m.tab().xOffset = min(m.tab().xOffset, max(0, widestLine(m.tab().content)-m.layout.ViewportWidth))
+			raw, _ := m.fileSource()
+			gutter := gutterWidth(strings.Count(raw, "\n")+1, m.tab().showLineNumbers)
+			m.tab().content = scrollSideways(m.tab().content, gutter, m.tab().xOffset)
 		}
 	}
-	m.viewport.SetContent(m.fileContent)
+	m.tab().viewport.SetContent(m.tab().content)
+	m.tab().selection = nil // Its rows may have moved
+	m = m.indexDocument()
+	if line := m.tab().pendingLine; line > 0 && m.tab().loadedAs == m.tab().source() {
+		m = m.goToLine(line)
+	}
+	if anchor := m.tab().pendingAnchor; anchor != "" && m.tab().loadedAs == m.tab().source() {
+		m = m.goToAnchor(anchor)
+	}
+	return m, cmd
+}
+
+// renderFile renders the loaded content of the current tab: markdown through
+// glamour, anything else with line numbers and wrapping
+func (m Model) renderFile() string {
+	rawContent, _ := m.fileSource()
+
+	logger.Debug("rendering file", "path", m.tab().path, "viewport_width", m.layout.ViewportWidth, "fullscreen", m.layout.IsFullscreen)
 
-	// Debug: Check what we're setting
-	lines := strings.Split(m.fileContent, "\n")
-	for i, line := range lines[:min(3, len(lines))] {
-		debugLog("Content line %d: length %d, content: '%.120s'", i+1, len(line), line)
+	if m.rendersMarkdown() {
+		// Render markdown with Glamour (no line numbers, no manual wrapping)
+		return m.renderMarkdown(rawContent)
 	}
+	return layoutLines(rawContent, m.tab().showLineNumbers, m.layout.ViewportWidth, m.wrapMode)
+}
 
-	return m, nil
+// fileSource is the loaded content of the current tab and the file name used
+// to detect its type, empty for stdin
+func (m Model) fileSource() (content, filename string) {
+	if m.tab().path == StdinPath {
+		return string(m.stdin.data), ""
+	}
+	return string(m.tab().data), filepath.Base(m.tab().path)
 }
 
 func (m Model) handleFileSelection() (tea.Model, tea.Cmd) {
@@ -505,100 +1187,169 @@ func (m Model) handleFileSelection() (tea.Model, tea.Cmd) {
 	}
 
 	fileItem := selectedItem.(FileItem)
+	var cmd tea.Cmd
 
+	if !filebrowser.WithinRoot(fileItem.path, m.rootDir) {
+		m.auditf("denied %s (outside root)", fileItem.path)
+		m.tab().viewport.SetContent("Access denied: path is outside the served directory")
+		return m, nil
+	}
+
+	m = m.recordJump()
 	if fileItem.isDir {
+		m.auditf("cd %s", filebrowser.DisplayPath(fileItem.path, m.rootDir))
+
 		// Add current directory to history before changing
 		m.directoryHistory = append(m.directoryHistory, m.currentDir)
 
 		// Change directory
 		m.currentDir = fileItem.path
-		files := getFileList(m.currentDir)
-		m.list.SetItems(files)
-		m.list.Title = formatDirectoryPath(m.currentDir)
-		m.list.Select(0)
-		m.viewport.SetContent("Select a file to view its content")
-		m.fileContent = ""
-		m.currentFilePath = ""
-
-		// Recalculate layout since we cleared the current file (affects header display)
-		m.layout = m.CalculateLayout()
-		m.viewport.Width = m.layout.ViewportWidth
-		m.viewport.Height = m.layout.ViewportHeight
+		m, cmd = m.refreshNavigator()
+		cmd = tea.Batch(cmd, m.recordVisit(fileItem.path, true))
 	} else {
-		// Store current file path
-		m.currentFilePath = fileItem.path
+		m.auditf("open %s", filebrowser.DisplayPath(fileItem.path, m.rootDir))
+
+		// Show the file in its tab, opening one if needed, read afresh
+		m = m.openTab(fileItem.path)
+		m.tab().stale = true
+		m = m.focusSplit(m.activeSplit)
+		updated, renderCmd := m.rerenderCurrentFile()
+		return updated, tea.Batch(renderCmd, m.recordVisit(fileItem.path, false))
+	}
+
+	return m, cmd
+}
 
-		// Recalculate layout since we now have a file (affects header display)
-		m.layout = m.CalculateLayout()
-		m.viewport.Width = m.layout.ViewportWidth
-		m.viewport.Height = m.layout.ViewportHeight
+// setContentMode switches what the content pane shows for the current file
+func (m Model) setContentMode(mode ContentMode) (tea.Model, tea.Cmd) {
+	m.tab().gitLoad.end() // A history or diff on its way would replace the mode
+	m.tab().mode = mode
+	m.tab().viewport.GotoTop()
+	return m.rerenderCurrentFile()
+}
 
-		// Read file content
-		content, err := os.ReadFile(fileItem.path)
-		if err != nil {
-			m.fileContent = fmt.Sprintf("Error reading file: %v", err)
-		} else {
-			rawContent := string(content)
-			filename := filepath.Base(fileItem.path)
+// showHistory lists the commits touching the current file once git has found them
+func (m Model) showHistory() (tea.Model, tea.Cmd) {
+	m.auditf("history %s", filebrowser.DisplayPath(m.tab().path, m.rootDir))
+	return m.withSpinner(loadHistoryCmd(m.tab()))
+}
 
-			debugLog("handleFileSelection - Viewport width: %d", m.layout.ViewportWidth)
+// showSelectedCommit shows the file as of the commit selected in the history,
+// or the changes that commit made to it when patch is true
+func (m Model) showSelectedCommit(patch bool) (tea.Model, tea.Cmd) {
+	commit, ok := m.tab().history.SelectedItem().(CommitItem)
+	if !ok {
+		return m, nil
+	}
 
-			if isMarkdownFile(filename) {
-				// Render markdown with Glamour (no line numbers, no manual wrapping)
-				m.fileContent = m.renderMarkdown(rawContent)
-			} else {
-				// Step 1: Add line numbers if enabled, otherwise use raw content
-				var contentWithLineNumbers string
-				if m.showLineNumbers {
-					contentWithLineNumbers = addLineNumbers(rawContent)
-				} else {
-					contentWithLineNumbers = rawContent
-				}
+	if !patch {
+		m.tab().revision, m.tab().revisionPath = commit.hash, commit.path
+		return m.setContentMode(FileContent)
+	}
 
-				// Step 2: Wrap the final content
-				wrapWidth := m.layout.ViewportWidth
-				if wrapWidth > 0 {
-					m.fileContent = wordwrap.String(contentWithLineNumbers, wrapWidth)
-				} else {
-					m.fileContent = contentWithLineNumbers
-				}
-			}
+	path := m.tab().path
+	return m.withSpinner(loadDiffCmd(m.tab(), "loading commit", func() (*diffView, error) {
+		diff, err := commitPatch(path, commit.hash, commit.path)
+		if err == nil {
+			diff.title = "@ " + commit.shortHash() + " patch"
 		}
-		m.focusedPane = ContentPane
-		m.viewport.SetContent(m.fileContent)
+		return diff, err
+	}))
+}
+
+// showRevisionDiff replaces the content with a diff of the current file against revision
+func (m Model) showRevisionDiff(revision string) (tea.Model, tea.Cmd) {
+	// Anything that looks like an option could make git write files
+	if revision == "" || strings.HasPrefix(revision, "-") {
+		return m, nil
 	}
 
-	return m, nil
+	m.auditf("diff %s against %s", filebrowser.DisplayPath(m.tab().path, m.rootDir), revision)
+	path := m.tab().path
+	sideBySide := m.tab().diff != nil && m.tab().diff.sideBySide
+	return m.withSpinner(loadDiffCmd(m.tab(), "computing diff", func() (*diffView, error) {
+		diff, err := diffAgainstRevision(path, revision)
+		if err == nil {
+			diff.sideBySide = sideBySide
+		}
+		return diff, err
+	}))
 }
 
-// getContentTitle returns the title for the content pane
-func (m Model) getContentTitle() string {
-	if m.currentFilePath == "" {
-		return ""
+// diffMarkedFile opens a diff of the marked file against the selected one
+func (m Model) diffMarkedFile() (tea.Model, tea.Cmd) {
+	item, ok := m.list.SelectedItem().(FileItem)
+	if !ok || item.isDir || m.markedPath == "" || item.path == m.markedPath {
+		return m, nil
+	}
+	if !filebrowser.WithinRoot(item.path, m.rootDir) || !filebrowser.WithinRoot(m.markedPath, m.rootDir) {
+		return m, nil
 	}
-	return formatDirectoryPath(m.currentFilePath)
+
+	m.auditf("diff %s against %s", filebrowser.DisplayPath(item.path, m.rootDir), filebrowser.DisplayPath(m.markedPath, m.rootDir))
+	marked, title := m.markedPath, "vs "+filebrowser.DisplayPath(m.markedPath, m.rootDir)
+	m = m.openTab(item.path)
+	m = m.focusSplit(m.activeSplit)
+	load := loadDiffCmd(m.tab(), "computing diff", func() (*diffView, error) {
+		diff, err := diffFiles(marked, item.path)
+		if err == nil {
+			diff.title = title
+		}
+		return diff, err
+	})
+
+	// Show the file until the diff is ready
+	updated, cmd := m.rerenderCurrentFile()
+	m, load = updated.(Model).withSpinner(load)
+	return m, tea.Batch(cmd, load)
 }
 
-// getContentHeaderView creates a header view for the content pane
-func (m Model) getContentHeaderView(width int, borderColor lipgloss.Color) string {
-	title := m.getContentTitle()
-	if title == "" {
+// getContentTitle returns the title for a tab in the content pane
+func (m Model) getContentTitle(t *Tab) string {
+	if t.path == "" {
 		return ""
 	}
+	if t.path == StdinPath {
+		title := "stdin"
+		if m.stdin.trimmed {
+			title += " (last " + formatSize(MaxFileSize) + ")"
+		}
+		if !m.stdin.done {
+			title += " (reading…)"
+		}
+		return title
+	}
+	title := filebrowser.DisplayPath(t.path, m.rootDir)
+	if t.revision != "" && t.mode != DiffContent {
+		title += " @ " + t.revision[:min(7, len(t.revision))]
+	}
 
-	// Title style with border like pager example, using provided border color
-	titleStyle := func() lipgloss.Style {
-		b := lipgloss.RoundedBorder()
-		b.Right = "├"
-		b.Left = "┤"
-		return lipgloss.NewStyle().BorderStyle(b).BorderForeground(borderColor).Padding(0, 1)
-	}()
+	switch t.mode {
+	case DiffContent:
+		title += " " + t.diff.title
+	case BlameContent:
+		title += " (blame)"
+	case HistoryContent:
+		title += " (history)"
+	}
+	if t.load.active() || t.gitLoad.active() {
+		title += " " + m.spinner.View()
+	}
+	return title
+}
+
+// getContentHeaderView creates a header view for a content split
+func (m Model) getContentHeaderView(split *Split, width int, borderColor lipgloss.Color) string {
+	if split.tab().path == "" {
+		return ""
+	}
 
-	titleRendered := titleStyle.Render(title)
+	// Tab labels boxed like the pager example title, using provided border color
+	titleRendered, _ := m.tabBar(split, width-2, borderColor)
 
 	// Style the line with the same border color
 	lineStyle := lipgloss.NewStyle().Foreground(borderColor)
-	lineWidth := max(0, width-lipgloss.Width(titleRendered)) - 2
+	lineWidth := max(0, width-lipgloss.Width(titleRendered)-2)
 
 	line := lineStyle.Render(strings.Repeat("─", lineWidth))
 	return lipgloss.JoinHorizontal(
@@ -613,20 +1364,12 @@ func (m Model) getContentHeaderView(width int, borderColor lipgloss.Color) strin
 
 // getContentHeaderViewFullscreen creates a header view for the content pane in fullscreen mode
 func (m Model) getContentHeaderViewFullscreen(width int) string {
-	title := m.getContentTitle()
-	if title == "" {
+	if m.tab().path == "" {
 		return ""
 	}
 
-	// Title style with border like pager example, using provided border color
-	titleStyle := func() lipgloss.Style {
-		b := lipgloss.RoundedBorder()
-		b.Right = "├"
-		b.Left = "┤"
-		return lipgloss.NewStyle().BorderStyle(b).Padding(0, 1)
-	}()
-
-	titleRendered := titleStyle.Render(title)
+	// Tab labels boxed like the pager example title
+	titleRendered, _ := m.tabBar(m.split(), width-1, "")
 	// Style the line with the same border color
 	lineStyle := lipgloss.NewStyle()
 	line := lineStyle.Render(strings.Repeat("─", max(0, width-lipgloss.Width(titleRendered))-1))
@@ -638,98 +1381,119 @@ func (m Model) getContentHeaderViewFullscreen(width int) string {
 	)
 }
 
+// contentView renders the body of a content split
+func (m Model) contentView(i int) string {
+	t := m.splits[i].tab()
+	var view string
+	switch {
+	case t.mode == HistoryContent:
+		return t.history.View()
+	case t.selection != nil || t.focusedLink > 0:
+		view = highlightedView(t)
+	default:
+		view = t.viewport.View()
+	}
+	if t.showsOutline() {
+		outline := m.outlineView(t, m.layout.Splits[i].OutlineWidth, t.viewport.Height)
+		view = lipgloss.JoinHorizontal(lipgloss.Top, view, outline)
+	}
+	return view
+}
+
 func (m Model) View() string {
 	if m.width == 0 || m.height == 0 {
 		return "Loading..."
 	}
 
-	// Generate help text
-	helpText := m.getHelpText()
+	// Generate help text, cut to one line so it cannot push the panes down
+	helpText := ansi.Truncate(m.getHelpText(), m.width, "…")
+
+	if m.showLogs {
+		return helpText + "\n" + m.logPanelView()
+	}
 
 	// Handle fullscreen mode for content pane
 	if m.layout.IsFullscreen {
-		debugLog("Fullscreen rendering - Terminal width: %d, Viewport width: %d", m.layout.TerminalWidth, m.layout.ViewportWidth)
-
 		contentHeader := m.getContentHeaderViewFullscreen(m.layout.TerminalWidth)
 		if contentHeader != "" {
-			return helpText + "\n" + contentHeader + "\n" + m.viewport.View()
+			return helpText + "\n" + contentHeader + "\n" + m.contentView(m.activeSplit)
 		} else {
-			return helpText + "\n" + m.viewport.View()
+			return helpText + "\n" + m.contentView(m.activeSplit)
 		}
 	}
 
 	// Use calculated layout dimensions for normal mode
 
 	// Style the left pane (navigator)
-	var leftStyle lipgloss.Style
-	if m.mode == PaneSelectionMode && m.selectedPane == NavigatorPane {
-		leftStyle = selectedBorderStyle
-	} else if m.mode == NavigatorMode && m.focusedPane == NavigatorPane {
-		leftStyle = focusedBorderStyle
-	} else {
-		leftStyle = unfocusedBorderStyle
-	}
-
-	// Style the right pane (content)
-	var rightStyle lipgloss.Style
-	if m.mode == PaneSelectionMode && m.selectedPane == ContentPane {
-		rightStyle = selectedBorderStyle
-	} else if m.mode == NavigatorMode && m.focusedPane == ContentPane {
-		rightStyle = focusedBorderStyle
-	} else {
-		rightStyle = unfocusedBorderStyle
-	}
+	leftStyle := m.theme.borderStyle(m.paneBorderColor(NavigatorPane))
 
 	// Create the panes
 	leftPane := leftStyle.
 		Width(m.layout.LeftPaneWidth).
 		Height(m.layout.LeftPaneHeight).
-		Render(m.list.View())
-
-	// Determine border color for content header
-	var headerBorderColor lipgloss.Color
-	if m.mode == PaneSelectionMode && m.selectedPane == ContentPane {
-		headerBorderColor = lipgloss.Color("51") // Cyan
-	} else if m.mode == NavigatorMode && m.focusedPane == ContentPane {
-		headerBorderColor = lipgloss.Color("42") // Green
-	} else {
-		headerBorderColor = lipgloss.Color("240") // White/Gray
+		Render(m.navigatorView())
+
+	// Create the content splits side by side or stacked
+	splitViews := make([]string, len(m.splits))
+	for i := range m.splits {
+		splitViews[i] = m.renderSplit(i)
+	}
+	rightPane := lipgloss.JoinHorizontal(lipgloss.Top, splitViews...)
+	if m.splitDirection == SplitHorizontal {
+		rightPane = lipgloss.JoinVertical(lipgloss.Left, splitViews...)
 	}
 
+	// Combine panes, with the parent directory first in the Miller layout
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
+	if m.navigatorCollapsed {
+		panes = rightPane
+	} else if m.layoutMode == MillerLayout {
+		parentView := ""
+		if len(m.parentList.Items()) > 0 {
+			parentView = m.parentList.View()
+		}
+		parentPane := m.theme.borderStyle(m.theme.UnfocusedBorder).
+			Width(m.layout.ParentPaneWidth).
+			Height(m.layout.LeftPaneHeight).
+			Render(parentView)
+		panes = lipgloss.JoinHorizontal(lipgloss.Top, parentPane, leftPane, rightPane)
+	}
+
+	// Add help text at the top
+	return helpText + "\n" + panes
+}
+
+// renderSplit renders content split i with its border and tab header
+func (m Model) renderSplit(i int) string {
+	split := m.splits[i]
+	splitLayout := m.layout.Splits[i]
+
+	// Style the right pane (content), sharing its colour with the content header
+	headerBorderColor := m.paneBorderColor(splitPane(i))
+	rightStyle := m.theme.borderStyle(headerBorderColor)
+
 	// Create content pane manually with integrated header border
-	var rightPane string
-	if m.layout.HasContentHeader {
+	if splitLayout.HasContentHeader {
 		// Create header as top border
-		contentHeader := m.getContentHeaderView(m.layout.RightPaneWidth, headerBorderColor)
+		contentHeader := m.getContentHeaderView(split, splitLayout.PaneWidth, headerBorderColor)
 
 		// Create sides and bottom border with proper border style
-		b := lipgloss.RoundedBorder()
-		b.Top = ""
-		b.TopLeft = ""
-		b.TopRight = ""
-
 		borderStyle := lipgloss.NewStyle().
-			BorderStyle(b).
+			Border(lipgloss.RoundedBorder(), false, true, true, true). // The header is the top border
 			BorderForeground(headerBorderColor).
-			Width(m.layout.RightPaneWidth).
-			Height(m.layout.ViewportHeight) // Use viewport height directly - it's already calculated correctly
+			Width(splitLayout.PaneWidth).
+			Height(splitLayout.ViewportHeight) // Use viewport height directly - it's already calculated correctly
 
-		contentBody := borderStyle.Render(m.viewport.View())
-		rightPane = contentHeader + contentBody // no newline as contentBody already has a newline
-	} else {
-		// No header, use normal border
-		rightPane = rightStyle.
-			Width(m.layout.RightPaneWidth).
-			Height(m.layout.RightPaneHeight).
-			Padding(ContentPadding).
-			Render(m.viewport.View())
+		contentBody := borderStyle.Render(m.contentView(i))
+		return contentHeader + "\n" + contentBody
 	}
 
-	// Combine panes
-	panes := lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
-
-	// Add help text at the top
-	return helpText + "\n" + panes
+	// No header, use normal border
+	return rightStyle.
+		Width(splitLayout.PaneWidth).
+		Height(splitLayout.PaneHeight).
+		Padding(ContentPadding).
+		Render(m.contentView(i))
 }
 
 func min(a, b int) int {
@@ -746,20 +1510,24 @@ func max(a, b int) int {
 	return b
 }
 
-// isMarkdownFile checks if a file is a markdown file based on extension
-func isMarkdownFile(filename string) bool {
+// isMarkdownFile checks if a file is a markdown file based on extension,
+// sniffing the content instead when there is no file name
+func isMarkdownFile(filename, content string) bool {
+	if filename == "" {
+		return looksLikeMarkdown(content)
+	}
 	ext := strings.ToLower(filepath.Ext(filename))
 	return ext == ".md" || ext == ".markdown"
 }
 
 // renderMarkdown renders markdown content using Glamour with proper width
 func (m Model) renderMarkdown(content string) string {
-	// Create a new renderer with the current viewport width for proper wrapping
-	debugLog("renderMarkdown - Using viewport width: %d for markdown rendering", m.layout.ViewportWidth)
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(m.layout.ViewportWidth),
-	)
+	// Use a renderer for the current viewport width for proper wrapping
+	wrapWidth := m.layout.ViewportWidth
+	if m.markdownWidth > 0 {
+		wrapWidth = min(wrapWidth, m.markdownWidth)
+	}
+	renderer, err := m.renderCache.markdownRenderer(m.theme, wrapWidth)
 	if err != nil {
 		// Fall back to raw content if rendering fails
 		return content
@@ -773,97 +1541,323 @@ func (m Model) renderMarkdown(content string) string {
 	return rendered
 }
 
-// addLineNumbers adds simple line numbers to content without any wrapping logic
-func addLineNumbers(originalContent string) string {
-	originalLines := strings.Split(originalContent, "\n")
-
-	if len(originalLines) == 0 {
-		return originalContent
+func (m Model) getHelpText() string {
+	if m.promptActive() {
+		return m.prompt.View()
 	}
-
-	// Calculate the width needed for line numbers
-	maxLineNum := len(originalLines)
-	lineNumWidth := len(strconv.Itoa(maxLineNum))
-
-	var result strings.Builder
-
-	// Process each original line individually
-	for lineNum, originalLine := range originalLines {
-		// Add line number to each line
-		lineNumStr := fmt.Sprintf("%*d", lineNumWidth, lineNum+1)
-		lineWithNumber := fmt.Sprintf("%s │ %s", lineNumStr, originalLine)
-		result.WriteString(lineWithNumber)
-
-		// Add newline except for the last line
-		if lineNum < len(originalLines)-1 {
-			result.WriteString("\n")
-		}
+	switch m.pendingMark {
+	case markSet:
+		return " Set mark: press a letter to name it, esc to cancel"
+	case markJump:
+		return " Jump to mark: press its letter, esc to cancel"
+	case markBookmark:
+		return " Bookmark: press a letter to name it, esc to cancel"
+	case markGoBookmark:
+		return " Jump to bookmark " + strings.Join(m.places.bookmarkLetters(), " ") + ": press its letter, esc to cancel"
+	}
+	if m.notice != "" {
+		return " " + m.notice
 	}
 
-	return result.String()
-}
-
-func (m Model) getHelpText() string {
 	// Style for highlighted keys
 	keyStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("0")).    // Black text
-		Background(lipgloss.Color("#ccc")). // White background
-		Padding(0, 1)                       // Small padding around text
+		Foreground(m.theme.HelpKeyText).
+		Background(m.theme.HelpKey).
+		Padding(0, 1) // Small padding around text
 
 	// Helper function to format key:action pairs
 	formatHint := func(key, action string) string {
 		return keyStyle.Render(key) + ":" + action
 	}
 
+	// Helper function to format the keys of the active keymap, e.g. "↑/↓:navigate".
+	// A single binding shows its help keys, several show their first key each.
+	hint := func(action string, bindings ...key.Binding) string {
+		var keys []string
+		for _, b := range bindings {
+			if !b.Enabled() {
+				continue
+			}
+			if len(bindings) == 1 {
+				keys = append(keys, b.Help().Key)
+			} else {
+				keys = append(keys, displayKey(b.Keys()[0]))
+			}
+		}
+		if len(keys) == 0 {
+			return ""
+		}
+		return formatHint(strings.Join(keys, "/"), action)
+	}
+
 	var hints []string
+	k := m.keys
 
 	// Common controls
-	hints = append(hints, formatHint("q/ctrl+c", "quit"))
+	hints = append(hints, hint("quit", k.Quit))
 
-	if m.isFullscreen {
+	if m.showLogs {
+		hints = append(hints, hint("scroll", k.Up, k.Down), hint("close log", k.LogPanel, k.PaneSelection))
+	} else if m.isFullscreen {
 		// Fullscreen mode
-		hints = append(hints, formatHint("f/esc", "exit fullscreen"))
-		if m.focusedPane == ContentPane {
-			hints = append(hints, formatHint("↑↓", "scroll"), formatHint("l", "toggle line numbers"))
+		hints = append(hints, hint("exit fullscreen", k.Fullscreen, k.PaneSelection))
+		if m.focusedPane.IsContent() {
+			hints = append(hints, hint("scroll", k.Up, k.Down), hint("toggle line numbers", k.ToggleLineNumbers))
 		}
 	} else if m.mode == PaneSelectionMode {
 		// Pane selection mode
-		hints = append(hints, formatHint("←→", "select pane"), formatHint("enter", "focus"), formatHint("esc", "back"))
+		hints = append(hints, hint("select pane", k.Left, k.Right), hint("focus", k.Open), hint("back", k.PaneSelection))
 	} else {
 		// Normal mode
-		hints = append(hints, formatHint("esc", "pane selection"))
+		hints = append(hints, hint("pane selection", k.PaneSelection))
 
 		switch m.focusedPane {
 		case NavigatorPane:
-			hints = append(hints, formatHint("↑↓", "navigate"), formatHint("enter", "select"))
+			if m.bookmarkList {
+				hints = append(hints, hint("navigate", k.Up, k.Down), hint("jump", k.Open), hint("remove", k.RemoveBookmark), hint("close", k.Bookmarks, k.PaneSelection))
+				break
+			}
+			hints = append(hints, hint("navigate", k.Up, k.Down))
+			if m.picker == nil {
+				hints = append(hints, hint("select", k.Open))
+			}
+			if m.layoutMode == MillerLayout {
+				hints = append(hints, hint("parent/open", k.Left, k.Right))
+			}
 			if len(m.directoryHistory) > 0 {
-				hints = append(hints, formatHint("z", "back"))
+				hints = append(hints, hint("back", k.Back))
+			}
+			switch {
+			case m.picker != nil && m.picker.multi:
+				hints = append(hints, hint(fmt.Sprintf("pick (%d)", len(m.picker.selected)), k.Open), hint("toggle", k.Mark))
+			case m.picker != nil:
+				hints = append(hints, hint("pick", k.Open))
+			default:
+				hints = append(hints, hint("mark", k.Mark), hint("bookmark", k.Bookmark))
+			}
+			hints = append(hints, hint("layout", k.ToggleLayout), hint("resize", k.ShrinkNavigator, k.GrowNavigator), hint("hide", k.ToggleNavigator))
+			if m.markedPath != "" {
+				hints = append(hints, hint("diff with "+filepath.Base(m.markedPath), k.Diff))
+			}
+		default:
+			if m.tab().selection != nil {
+				hints = append(hints, hint("extend selection", k.Up, k.Down, k.Left, k.Right), hint("copy", k.CopySelection), hint("cancel", k.PaneSelection))
+				break
+			}
+			if o := m.tab().outline; o != nil && o.focused {
+				hints = append(hints, hint("headings", k.Up, k.Down), hint("read", k.Open), hint("close outline", k.ToggleOutline))
+				break
+			}
+			hints = append(hints, hint("scroll", k.Up, k.Down), hint("back to navigator", k.Left))
+			if m.tab().focusedLink > 0 {
+				hints = append(hints, hint("follow "+m.tab().links[m.tab().focusedLink-1].target, k.Open))
+			}
+			if m.navigatorCollapsed {
+				hints = append(hints, hint("show navigator", k.ToggleNavigator))
+			}
+			switch m.tab().mode {
+			case DiffContent:
+				hints = append(hints, hint("next/prev hunk", k.NextHunk, k.PrevHunk), hint("side-by-side", k.SideBySide), hint("close diff", k.Diff))
+			case BlameContent:
+				hints = append(hints, hint("close blame", k.Blame))
+			case HistoryContent:
+				hints = append(hints, hint("view at commit", k.Open), hint("patch", k.Patch), hint("close history", k.History))
+			default:
+				hints = append(hints, hint("toggle line numbers", k.ToggleLineNumbers), hint("wrap: "+m.wrapMode.String(), k.ToggleWrap),
+					hint("go to line", k.GoToLine), hint("mark/jump to mark", k.SetMark, k.JumpToMark), hint("select", k.Visual, k.VisualLine), hint("copy path:line", k.CopyLocation))
+				if m.scrollsSideways() {
+					hints = append(hints, hint("scroll sideways", k.Left, k.Right))
+				}
+				if m.isMarkdown() {
+					hints = append(hints, hint("outline", k.ToggleOutline), hint("next/prev link", k.NextLink, k.PrevLink), hint("source/rendered", k.ToggleRaw))
+				}
+				if m.tab().path != StdinPath {
+					hints = append(hints, hint("diff HEAD", k.Diff), hint("diff revision", k.DiffRevision), hint("blame", k.Blame), hint("history", k.History))
+				}
+			}
+			if len(m.jumps) > 0 {
+				hints = append(hints, hint("jump back/forward", k.JumpBack, k.JumpForward))
 			}
-		case ContentPane:
-			hints = append(hints, formatHint("↑↓", "scroll"), formatHint("←", "back to navigator"), formatHint("l", "toggle line numbers"), formatHint("f", "fullscreen"))
+			if len(m.split().tabs) > 1 {
+				hints = append(hints, hint("next tab", k.NextTab))
+			}
+			hints = append(hints, hint("close tab", k.CloseTab), hint("split (all side by side/stacked)", k.SplitVertical, k.SplitHorizontal))
+			if len(m.splits) > 1 {
+				lockHint := "lock scroll"
+				if m.scrollLock {
+					lockHint = "unlock scroll"
+				}
+				hints = append(hints, hint("next pane", k.NextPane), hint("close split", k.CloseSplit), hint(lockHint, k.ScrollLock))
+			}
+			hints = append(hints, hint("fullscreen", k.Fullscreen))
 		}
 	}
 
+	// Drop hints for actions left unbound
+	shown := hints[:0]
+	for _, h := range hints {
+		if h != "" {
+			shown = append(shown, h)
+		}
+	}
+	hints = shown
+
 	return " " + strings.Join(hints, "    ") // 4 spaces between items
 }
 
-// debugLog writes debug information to a file
-func debugLog(format string, args ...interface{}) {
-	f, err := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+// auditf records a session event when serving over SSH
+func (m Model) auditf(format string, args ...interface{}) {
+	if m.audit == nil {
 		return
 	}
-	defer f.Close()
-
-	timestamp := time.Now().Format("15:04:05.000")
-	message := fmt.Sprintf(format, args...)
-	f.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, message))
+	m.audit.Printf(format, args...)
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	os.Exit(run())
+}
+
+// run is the program, returning its exit status once deferred cleanup has run
+func run() int {
+	sshAddr := flag.String("ssh", "", "serve the viewer over SSH on this address (e.g. :2222)")
+	sshRoot := flag.String("root", "", "directory served over SSH (defaults to the current directory)")
+	hostKey := flag.String("host-key", ".ssh/bubbletest_ed25519", "SSH host key path, generated if missing")
+	authorizedKeys := flag.String("authorized-keys", "", "only allow public keys listed in this authorized_keys file")
+	auditLog := flag.String("audit-log", "", "append per-session audit entries to this file (defaults to stderr)")
+	configPath := flag.String("config", defaultConfigPath(), "config file")
+	printConfig := flag.Bool("print-config", false, "print the effective settings as a config file and exit")
+	pick := flag.Bool("pick", false, "pick files in the navigator, print their paths and exit")
+	dirsOnly := flag.Bool("dirs-only", false, "pick directories instead of files")
+	multi := flag.Bool("multi", false, "pick several entries, toggled with the mark key")
+	extensions := flag.String("ext", "", "only list files with these comma-separated extensions when picking, e.g. .go,.md")
+	cdFile := flag.String("cd-file", "", "write the directory navigated to on exit to this file, for a shell wrapper to cd into")
+	logFile := flag.String("log-file", os.Getenv("BUBBLETEST_LOG_FILE"), "append diagnostics to this file, rotated as it grows (or $BUBBLETEST_LOG_FILE)")
+	logLevel := flag.String("log-level", envOr("BUBBLETEST_LOG_LEVEL", "info"), "least severe log entries kept: debug, info, warn or error (or $BUBBLETEST_LOG_LEVEL)")
+	logFormat := flag.String("log-format", envOr("BUBBLETEST_LOG_FORMAT", "text"), "log file format: text or json (or $BUBBLETEST_LOG_FORMAT)")
+	logMaxSize := flag.Int("log-max-size", 10, "rotate the log file once it reaches this many megabytes")
+	logBackups := flag.Int("log-backups", 3, "rotated log files to keep")
+	var overrides Config
+	registerConfigFlags(flag.CommandLine, &overrides)
+	flag.Parse()
+
+	logs, closeLog, err := setupLogging(logOptions{
+		file:       *logFile,
+		level:      *logLevel,
+		format:     *logFormat,
+		maxSize:    int64(*logMaxSize) << 20,
+		maxBackups: *logBackups,
+	})
+	if err != nil {
 		fmt.Printf("Error: %v", err)
-		os.Exit(1)
+		return 1
+	}
+	defer closeLog()
+
+	// Flags given on the command line take precedence over the config file
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v", err)
+		return 1
+	}
+	applyFlags(flag.CommandLine, &cfg, overrides)
+	settings, err := cfg.resolve()
+	if err != nil {
+		fmt.Printf("Error: %v", err)
+		return 1
+	}
+
+	if *printConfig {
+		if err := settings.printConfig(os.Stdout); err != nil {
+			fmt.Printf("Error: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *sshAddr != "" {
+		cfg := sshConfig{
+			addr:           *sshAddr,
+			root:           *sshRoot,
+			hostKeyPath:    *hostKey,
+			authorizedKeys: *authorizedKeys,
+			auditLogPath:   *auditLog,
+			settings:       settings,
+		}
+		if err := runSSHServer(cfg); err != nil {
+			fmt.Printf("Error: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	if (*dirsOnly || *multi || *extensions != "") && !*pick {
+		fmt.Printf("Error: -dirs-only, -multi and -ext only apply with -pick")
+		return 1
+	}
+
+	// Piped input is shown like a pager, with keys read from the terminal instead
+	var stdin io.Reader
+	options := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
+	if stdinIsPiped() && !*pick {
+		stdin = os.Stdin
+		options = append(options, tea.WithInputTTY())
+	}
+
+	// Draw on the terminal when stdout is captured, as in $(bubbletest -pick)
+	terminal := io.Writer(os.Stdout)
+	if !stdoutIsTerminal() {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: opening terminal: %v", err)
+			return 1
+		}
+		defer tty.Close()
+		terminal = tty
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+		options = append(options, tea.WithOutput(tty))
+	}
+
+	if *pick {
+		// Picking is not a session to come back to
+		settings.cfg.RestoreSession, settings.cfg.Session = false, ""
+	}
+	m, err := initialModel(settings, flag.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(terminal, "Error: %v", err)
+		return 1
+	}
+	m.clipboard = terminal
+	m.systemClipboard = true
+	m.logs = logs
+	logger.Info("started", "dir", m.currentDir, "files", len(flag.Args()), "stdin", stdin != nil)
+	if *pick {
+		m = m.startPicking(*dirsOnly, *multi, parseExtensions(*extensions))
+	}
+
+	p := tea.NewProgram(m, options...)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(terminal, "Error: %v", err)
+		return 1
+	}
+	result := final.(Model)
+
+	if err := result.saveSession(); err != nil {
+		logger.Warn("saving session failed", "path", result.sessionPath, "err", err)
+	}
+	if *cdFile != "" {
+		if err := os.WriteFile(*cdFile, []byte(result.currentDir+"\n"), 0644); err != nil {
+			fmt.Fprintf(terminal, "Error: %v", err)
+			return 1
+		}
+	}
+	if *pick {
+		if result.picker.result == nil {
+			return 1 // Cancelled
+		}
+		for _, path := range result.picker.result {
+			fmt.Println(path)
+		}
 	}
+	return 0
 }
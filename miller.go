@@ -0,0 +1,225 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// LayoutMode is the arrangement of panes outside fullscreen
+type LayoutMode int
+
+const (
+	TwoPaneLayout LayoutMode = iota // Navigator and content
+	MillerLayout                    // Parent directory, navigator and a preview of the selection
+)
+
+// MaxParentPaneWidth caps the parent directory column in the Miller layout
+const MaxParentPaneWidth = 30
+
+// parseLayoutMode maps a layout name from the command line to a LayoutMode
+func parseLayoutMode(name string) (LayoutMode, bool) {
+	switch name {
+	case "two-pane", "":
+		return TwoPaneLayout, true
+	case "miller":
+		return MillerLayout, true
+	}
+	return TwoPaneLayout, false
+}
+
+// newParentList creates the read-only list showing the parent directory
+func newParentList(t Theme) list.Model {
+	l := list.New(nil, newFileDelegate(t), 0, 0)
+	t.styleList(&l)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowHelp(false)
+	return l
+}
+
+// parentDir returns the directory the parent column shows, or "" at the top of the tree
+func (m Model) parentDir() string {
+	parent := filepath.Dir(m.currentDir)
+	if parent == m.currentDir || (m.rootDir != "" && filepath.Clean(m.currentDir) == filepath.Clean(m.rootDir)) {
+		return ""
+	}
+	return parent
+}
+
+// refreshParentList lists the parent of currentDir in the background, leaving
+// the column empty at the top of the tree
+func (m Model) refreshParentList() (Model, tea.Cmd) {
+	parent := m.parentDir()
+	if parent == "" {
+		m.parentLoad.end()
+		return m.showParentListing("", nil), nil
+	}
+	return m.withSpinner(m.listDirCmd(&m.parentLoad, parent))
+}
+
+// showParentListing fills the parent column with the entries of dir, selecting currentDir
+func (m Model) showParentListing(dir string, entries []filebrowser.Entry) Model {
+	if dir == "" {
+		m.parentList.SetItems(nil)
+		m.parentList.Title = ""
+		return m
+	}
+
+	items := applyGitStatus(fileItems(entries), m.gitStatus)
+	m.parentList.SetItems(items)
+	m.parentList.Title = filebrowser.DisplayPath(dir, m.rootDir)
+	for i, item := range items {
+		if item.(FileItem).path == m.currentDir {
+			m.parentList.Select(i)
+			break
+		}
+	}
+	return m
+}
+
+// toggleLayoutMode switches between the two-pane and Miller layouts
+func (m Model) toggleLayoutMode() (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if m.layoutMode == MillerLayout {
+		m.layoutMode = TwoPaneLayout
+	} else {
+		m.layoutMode = MillerLayout
+		m, cmd = m.refreshParentList()
+	}
+
+	m = m.applyLayout()
+	updated, rerenderCmd := m.rerenderAllSplits()
+	m, previewCmd := updated.(Model).updatePreview()
+	return m, tea.Batch(cmd, rerenderCmd, previewCmd)
+}
+
+// applyLayout recalculates the layout and resizes the lists to match
+func (m Model) applyLayout() Model {
+	m.layout = m.CalculateLayout()
+	m.list.SetWidth(m.layout.ListWidth)
+	m.list.SetHeight(m.layout.ListHeight)
+	m.parentList.SetWidth(m.layout.ParentListWidth)
+	m.parentList.SetHeight(m.layout.ListHeight)
+	return m
+}
+
+// updatePreview shows the navigator selection in the preview tab of the active
+// split while browsing in the Miller layout
+func (m Model) updatePreview() (Model, tea.Cmd) {
+	if m.layoutMode != MillerLayout || m.focusedPane != NavigatorPane {
+		return m, nil
+	}
+
+	item, ok := m.list.SelectedItem().(FileItem)
+	if !ok || item.name == ".." || !filebrowser.WithinRoot(item.path, m.rootDir) {
+		return m, nil
+	}
+	if m.tab().path == item.path {
+		return m, nil
+	}
+
+	m = m.openPreviewTab(item.path, item.isDir)
+	updated, cmd := m.rerenderCurrentFile()
+	return updated.(Model), cmd
+}
+
+// openPreviewTab shows path in the split's preview tab, which is replaced by the
+// next preview rather than accumulating like tabs opened on purpose
+func (m Model) openPreviewTab(path string, isDir bool) Model {
+	s := m.split()
+	for i, t := range s.tabs {
+		if t.path == path {
+			s.activeTab = i
+			m.layout = m.CalculateLayout()
+			return m.resizeActiveTab()
+		}
+	}
+
+	t := newTab(m.showLineNumbers)
+	t.path = path
+	t.isDir = isDir
+	t.preview = true
+
+	replace := -1
+	for i, existing := range s.tabs {
+		if existing.preview || existing.path == "" {
+			replace = i
+			break
+		}
+	}
+	if replace >= 0 {
+		s.tabs[replace].endLoads()
+		s.tabs[replace] = t
+		s.activeTab = replace
+	} else {
+		s.tabs = append(s.tabs, t)
+		s.activeTab = len(s.tabs) - 1
+	}
+
+	m.layout = m.CalculateLayout()
+	return m.resizeActiveTab()
+}
+
+// handleHierarchyNavigation moves up and down the directory tree from the
+// navigator with the left and right keys in the Miller layout, reporting
+// whether the key was used
+func (m Model) handleHierarchyNavigation(k keyPress) (tea.Model, tea.Cmd, bool) {
+	if m.layoutMode != MillerLayout || m.focusedPane != NavigatorPane {
+		return m, nil, false
+	}
+
+	switch {
+	case key.Matches(k, m.keys.Left):
+		updated, cmd := m.goToParentDirectory()
+		return updated, cmd, true
+	case key.Matches(k, m.keys.Right):
+		updated, cmd := m.handleFileSelection()
+		return updated, cmd, true
+	}
+	return m, nil, false
+}
+
+// goToParentDirectory moves the navigator up one level with the directory it
+// came from selected, stopping at the top of the tree
+func (m Model) goToParentDirectory() (tea.Model, tea.Cmd) {
+	parent := filepath.Dir(m.currentDir)
+	if parent == m.currentDir || !filebrowser.WithinRoot(parent, m.rootDir) ||
+		(m.rootDir != "" && filepath.Clean(m.currentDir) == filepath.Clean(m.rootDir)) {
+		return m, nil
+	}
+
+	m = m.recordJump()
+	m.auditf("cd %s", filebrowser.DisplayPath(parent, m.rootDir))
+	m.directoryHistory = append(m.directoryHistory, m.currentDir)
+
+	// Select the directory we came from once the parent is listed
+	m.selectOnListing = m.currentDir
+	m.currentDir = parent
+	m, cmd := m.refreshNavigator()
+	return m, tea.Batch(cmd, m.recordVisit(parent, true))
+}
+
+// renderDirectoryPreview lists a directory's entries for the preview column
+func renderDirectoryPreview(entries []filebrowser.Entry) string {
+	var lines []string
+	for _, entry := range entries {
+		if entry.Name == ".." {
+			continue
+		}
+		if entry.IsDir {
+			lines = append(lines, entry.Name+"/")
+		} else {
+			lines = append(lines, entry.Name)
+		}
+	}
+
+	if len(lines) == 0 {
+		return "Empty directory"
+	}
+	return strings.Join(lines, "\n")
+}
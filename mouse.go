@@ -0,0 +1,242 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DoubleClickInterval is the longest gap between two clicks on the same item that opens it
+const DoubleClickInterval = 400 * time.Millisecond
+
+// rect is a region of the screen, for mouse hit-testing
+type rect struct {
+	x, y, width, height int
+}
+
+func (r rect) contains(x, y int) bool {
+	return x >= r.x && x < r.x+r.width && y >= r.y && y < r.y+r.height
+}
+
+// navigatorRect returns where the navigator is drawn, including its border
+func (m Model) navigatorRect() rect {
+	if m.layout.IsFullscreen || m.navigatorCollapsed {
+		return rect{}
+	}
+	return rect{
+		x:      m.parentPaneRenderedWidth(),
+		y:      HelpTextHeight,
+		width:  m.layout.LeftPaneWidth + BorderWidth,
+		height: m.layout.LeftPaneHeight + BorderWidth,
+	}
+}
+
+// parentRect returns where the Miller layout's parent column is drawn, including its border
+func (m Model) parentRect() rect {
+	if m.layout.IsFullscreen || m.navigatorCollapsed {
+		return rect{}
+	}
+	return rect{
+		y:      HelpTextHeight,
+		width:  m.parentPaneRenderedWidth(),
+		height: m.layout.LeftPaneHeight + BorderWidth,
+	}
+}
+
+// splitRect returns where split i is drawn, including its border
+func (m Model) splitRect(i int) rect {
+	split := m.layout.Splits[i]
+	if !split.Visible {
+		return rect{}
+	}
+	if m.layout.IsFullscreen {
+		return rect{x: split.X, y: split.Y, width: m.layout.TerminalWidth, height: m.layout.TerminalHeight - split.Y}
+	}
+	return rect{x: split.X, y: split.Y, width: split.PaneWidth + BorderWidth, height: split.PaneHeight + BorderWidth}
+}
+
+// splitBodyTop returns the first screen row of split i's content, below its header or border
+func (m Model) splitBodyTop(i int) int {
+	split := m.layout.Splits[i]
+	if split.HasContentHeader {
+		return split.Y + ContentHeaderHeight
+	}
+	if m.layout.IsFullscreen {
+		return split.Y
+	}
+	return split.Y + 1 + ContentPadding
+}
+
+// splitBodyLeft returns the first screen column of split i's content, inside its border
+func (m Model) splitBodyLeft(i int) int {
+	split := m.layout.Splits[i]
+	switch {
+	case m.layout.IsFullscreen:
+		return split.X
+	case split.HasContentHeader:
+		return split.X + 1
+	}
+	return split.X + 1 + ContentPadding
+}
+
+// splitAt returns the index of the split under the pointer
+func (m Model) splitAt(x, y int) (int, bool) {
+	for i := range m.layout.Splits {
+		if m.splitRect(i).contains(x, y) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// listItemAt returns the index of the item drawn at row, counted from the top of the list
+func listItemAt(l list.Model, row int) (int, bool) {
+	if l.ShowTitle() {
+		row -= 1 + l.Styles.TitleBar.GetVerticalFrameSize()
+	}
+
+	delegate := list.NewDefaultDelegate()
+	perItem := delegate.Height() + delegate.Spacing()
+	if row < 0 || row%perItem >= delegate.Height() || row/perItem >= l.Paginator.PerPage {
+		return 0, false
+	}
+
+	index := l.Paginator.Page*l.Paginator.PerPage + row/perItem
+	if index >= len(l.VisibleItems()) {
+		return 0, false
+	}
+	return index, true
+}
+
+// handleMouse dispatches mouse events to the pane under the pointer, using the
+// rectangles from the computed layout
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if updated, cmd, ok := m.handleDividerDrag(msg); ok {
+		return updated, cmd
+	}
+	if updated, cmd, ok := m.handleSelectionDrag(msg); ok {
+		return updated, cmd
+	}
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		return m.handleWheel(msg)
+	case tea.MouseButtonLeft, tea.MouseButtonMiddle:
+		m.notice = ""
+		if updated, cmd, ok := m.handleTabClick(msg); ok {
+			return updated, cmd
+		}
+		if msg.Button == tea.MouseButtonLeft {
+			return m.handleClick(msg)
+		}
+	}
+	return m, nil
+}
+
+// handleWheel scrolls whichever pane is under the pointer without focusing it
+func (m Model) handleWheel(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	up := msg.Button == tea.MouseButtonWheelUp
+
+	if m.navigatorRect().contains(msg.X, msg.Y) {
+		if up {
+			m.list.CursorUp()
+		} else {
+			m.list.CursorDown()
+		}
+		return m.updatePreview()
+	}
+
+	i, ok := m.splitAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	t := m.splits[i].tab()
+	if t.mode == HistoryContent {
+		if up {
+			t.history.CursorUp()
+		} else {
+			t.history.CursorDown()
+		}
+		return m, nil
+	}
+
+	offset := t.viewport.YOffset
+	if up {
+		t.viewport.ScrollUp(t.viewport.MouseWheelDelta)
+	} else {
+		t.viewport.ScrollDown(t.viewport.MouseWheelDelta)
+	}
+	return m.syncScroll(i, t.viewport.YOffset-offset), nil
+}
+
+// handleClick focuses the clicked pane, bypassing pane selection, and selects
+// the clicked list item; a second click on the same item opens it
+func (m Model) handleClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.parentRect().contains(msg.X, msg.Y) {
+		m.mode = NavigatorMode
+		m.focusedPane = NavigatorPane
+		index, ok := listItemAt(m.parentList, msg.Y-HelpTextHeight-1)
+		if !ok {
+			return m, nil
+		}
+
+		// Step out into the parent and select the clicked entry there once it is listed
+		clicked := m.parentList.VisibleItems()[index].(FileItem)
+		updated, cmd := m.goToParentDirectory()
+		m = updated.(Model)
+		m.selectOnListing = clicked.path
+		return m, cmd
+	}
+
+	if m.navigatorRect().contains(msg.X, msg.Y) {
+		m.mode = NavigatorMode
+		m.focusedPane = NavigatorPane
+		index, ok := listItemAt(m.list, msg.Y-HelpTextHeight-1)
+		if !ok {
+			return m, nil
+		}
+
+		m.list.Select(index)
+		if m.isDoubleClick(index) {
+			return m.handleFileSelection()
+		}
+		return m.updatePreview()
+	}
+
+	i, ok := m.splitAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+	m.mode = NavigatorMode
+	m = m.focusSplit(i)
+
+	if m.tab().mode == HistoryContent {
+		index, ok := listItemAt(m.tab().history, msg.Y-m.splitBodyTop(i))
+		if !ok {
+			return m, nil
+		}
+		m.tab().history.Select(index)
+		if m.isDoubleClick(index) {
+			return m.showSelectedCommit(false)
+		}
+	}
+	return m, nil
+}
+
+// isDoubleClick records a click on a list item and reports whether it quickly
+// followed another click on the same item
+func (m *Model) isDoubleClick(index int) bool {
+	now := time.Now()
+	double := index == m.lastClickIndex && now.Sub(m.lastClickAt) < DoubleClickInterval
+	m.lastClickIndex = index
+	m.lastClickAt = now
+	if double {
+		m.lastClickAt = time.Time{} // A third click starts over
+	}
+	return double
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// MaxOutlineWidth is the widest the outline sidebar gets, taking at most a third of the split
+const MaxOutlineWidth = 30
+
+var (
+	atxHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	setextHeading = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	codeFence     = regexp.MustCompile("^ {0,3}(```|~~~)")
+	markdownLink  = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	inlineMarkup  = regexp.MustCompile("[*_`~]")
+)
+
+// heading is a markdown heading and the row of the tab it is shown on
+type heading struct {
+	level  int
+	text   string
+	anchor string // As GitHub derives it, for links to #anchor
+	line   int    // In the source, counted from zero
+	row    int
+}
+
+// docLink is a link in a markdown document and where its text is shown
+type docLink struct {
+	text, target string
+	row, col     int // Row -1 when it could not be found in the rendering
+	width        int
+}
+
+// outline is the state of a tab's heading sidebar while it is open
+type outline struct {
+	cursor  int
+	focused bool // Keys move through the headings rather than the document
+}
+
+// rendersMarkdown reports whether the current tab shows a rendered markdown
+// document. Stdin shows as source until it ends, rather than going through
+// glamour again for every chunk.
+func (m Model) rendersMarkdown() bool {
+	return m.isMarkdown() && !m.tab().raw && (m.tab().path != StdinPath || m.stdin.done)
+}
+
+// isMarkdown reports whether the current tab shows a markdown file, rendered or as source
+func (m Model) isMarkdown() bool {
+	t := m.tab()
+	if t.mode != FileContent || t.isDir || t.loadErr != nil || t.path == "" {
+		return false
+	}
+	content, filename := m.fileSource()
+	return isMarkdownFile(filename, content)
+}
+
+// plainText removes inline markup from markdown, leaving the text shown for it
+func plainText(markdown string) string {
+	markdown = markdownLink.ReplaceAllString(markdown, "$2")
+	return strings.TrimSpace(inlineMarkup.ReplaceAllString(markdown, ""))
+}
+
+// headingAnchor derives the anchor GitHub gives a heading: lower case, without
+// punctuation, words joined by hyphens
+func headingAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// parseHeadings lists the headings of a markdown document, leaving out code blocks
+func parseHeadings(source string) []heading {
+	var headings []heading
+	seen := make(map[string]int)
+	add := func(level int, text string, line int) {
+		text = plainText(text)
+		anchor := headingAnchor(text)
+		if n := seen[anchor]; n > 0 {
+			seen[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			seen[anchor] = 1
+		}
+		headings = append(headings, heading{level: level, text: text, anchor: anchor, line: line})
+	}
+
+	lines := strings.Split(source, "\n")
+	inCode := false
+	for i, line := range lines {
+		if codeFence.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		if match := atxHeading.FindStringSubmatch(line); match != nil {
+			add(len(match[1]), match[2], i)
+			continue
+		}
+		// An underlined heading, unless the line above is blank or a heading already
+		if match := setextHeading.FindStringSubmatch(line); match != nil && i > 0 &&
+			strings.TrimSpace(lines[i-1]) != "" && !atxHeading.MatchString(lines[i-1]) {
+			level := 1
+			if match[1][0] == '-' {
+				level = 2
+			}
+			add(level, lines[i-1], i-1)
+		}
+	}
+	return headings
+}
+
+// parseLinks lists the links of a markdown document, leaving out images and code blocks
+func parseLinks(source string) []docLink {
+	var links []docLink
+	inCode := false
+	for _, line := range strings.Split(source, "\n") {
+		if codeFence.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		for _, match := range markdownLink.FindAllStringSubmatch(line, -1) {
+			if match[1] == "" {
+				links = append(links, docLink{text: plainText(match[2]), target: match[3], row: -1})
+			}
+		}
+	}
+	return links
+}
+
+// sameWords reports whether line contains text, ignoring how spaces are laid out
+func sameWords(line, text string) bool {
+	return strings.Contains(strings.Join(strings.Fields(line), " "), strings.Join(strings.Fields(text), " "))
+}
+
+// locateHeadings finds the row each heading is rendered on, in order
+func locateHeadings(headings []heading, rendered []string) {
+	row := 0
+	for i := range headings {
+		headings[i].row = -1
+		for r := row; r < len(rendered); r++ {
+			if headings[i].text != "" && sameWords(rendered[r], headings[i].text) {
+				headings[i].row, row = r, r+1
+				break
+			}
+		}
+	}
+}
+
+// locateLinks finds where the text of each link is rendered, in order
+func locateLinks(links []docLink, rendered []string) {
+	row, col := 0, 0
+	for i := range links {
+		needle := links[i].text
+		if needle == "" {
+			needle = links[i].target
+		}
+		for r := row; r < len(rendered); r++ {
+			line := rendered[r]
+			from := 0
+			if r == row {
+				from = min(col, len(line))
+			}
+			if idx := strings.Index(line[from:], needle); idx >= 0 {
+				idx += from
+				links[i].row = r
+				links[i].col = ansi.StringWidth(line[:idx])
+				links[i].width = ansi.StringWidth(needle)
+				row, col = r, idx+len(needle)
+				break
+			}
+		}
+	}
+}
+
+// indexDocument finds the headings and links of the current tab's markdown
+// in its content, once rendered
+func (m Model) indexDocument() Model {
+	t := m.tab()
+	t.headings, t.links = nil, nil
+	if !m.isMarkdown() || (t.path != StdinPath && t.loadedAs != t.source()) {
+		t.focusedLink = 0
+		return m
+	}
+
+	source, _ := m.fileSource()
+	t.headings = parseHeadings(source)
+	if m.rendersMarkdown() {
+		rendered := strings.Split(ansi.Strip(t.content), "\n")
+		locateHeadings(t.headings, rendered)
+		t.links = parseLinks(source)
+		locateLinks(t.links, rendered)
+	} else {
+		starts := m.lineStarts()
+		for i := range t.headings {
+			t.headings[i].row = t.headings[i].line
+			if starts != nil {
+				t.headings[i].row = starts[t.headings[i].line]
+			}
+		}
+	}
+	if t.focusedLink > len(t.links) {
+		t.focusedLink = 0
+	}
+	if t.outline != nil {
+		t.outline.cursor = min(t.outline.cursor, max(0, len(t.headings)-1))
+	}
+	return m
+}
+
+// showsOutline reports whether the heading sidebar is shown beside the tab's content
+func (t *Tab) showsOutline() bool {
+	return t.outline != nil && t.mode == FileContent
+}
+
+// outlineWidth is how wide the outline sidebar is in a split whose content is width wide
+func outlineWidth(width int) int {
+	return min(MaxOutlineWidth, width/3)
+}
+
+// currentHeading is the index of the heading whose section is at the top of
+// the viewport, -1 above the first
+func (t *Tab) currentHeading() int {
+	current := -1
+	for i, h := range t.headings {
+		if h.row >= 0 && h.row <= t.viewport.YOffset {
+			current = i
+		}
+	}
+	return current
+}
+
+// outlineView renders the heading sidebar of a tab, width wide including its border
+func (m Model) outlineView(t *Tab, width, height int) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(m.theme.Muted).
+		Width(width - 1).
+		Height(height).
+		MaxHeight(height)
+
+	if len(t.headings) == 0 {
+		return style.Foreground(m.theme.Muted).Render(" No headings")
+	}
+
+	highlighted := t.currentHeading()
+	if t.outline.focused {
+		highlighted = t.outline.cursor
+	}
+	// Keep the highlighted heading in view
+	first := max(0, min(highlighted-height/2, len(t.headings)-height))
+
+	var lines []string
+	for i := first; i < min(len(t.headings), first+height); i++ {
+		h := t.headings[i]
+		line := ansi.Truncate(" "+strings.Repeat("  ", h.level-1)+h.text, width-1, "…")
+		switch {
+		case i == highlighted && t.outline.focused:
+			line = lipgloss.NewStyle().Foreground(m.theme.Accent).Bold(true).Render(line)
+		case i == highlighted:
+			line = lipgloss.NewStyle().Foreground(m.theme.Accent).Render(line)
+		case h.row < 0:
+			line = lipgloss.NewStyle().Foreground(m.theme.Muted).Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+// toggleOutline opens the outline focused, focuses it when open, and closes it
+// when focused
+func (m Model) toggleOutline() (tea.Model, tea.Cmd) {
+	t := m.tab()
+	switch {
+	case t.outline == nil:
+		if !m.isMarkdown() {
+			m.notice = "The outline lists the headings of markdown files"
+			return m, nil
+		}
+		t.outline = &outline{cursor: max(0, t.currentHeading()), focused: true}
+	case !t.outline.focused:
+		t.outline.focused = true
+		t.outline.cursor = max(0, t.currentHeading())
+		return m, nil
+	default:
+		t.outline = nil
+	}
+	// The document is re-wrapped for the room the sidebar leaves
+	m.layout = m.CalculateLayout()
+	m = m.resizeActiveTab()
+	return m.rerenderCurrentFile()
+}
+
+// handleOutlineKey moves through the headings while the outline is focused,
+// scrolling the document along, and reports whether the key was used
+func (m Model) handleOutlineKey(k keyPress) (tea.Model, tea.Cmd, bool) {
+	t := m.tab()
+	if !m.focusedPane.IsContent() || t.outline == nil || !t.outline.focused {
+		return m, nil, false
+	}
+
+	o := t.outline
+	switch {
+	case key.Matches(k, m.keys.ToggleOutline):
+		updated, cmd := m.toggleOutline()
+		return updated, cmd, true
+	case key.Matches(k, m.keys.Open, m.keys.PaneSelection, m.keys.Left):
+		o.focused = false // Back to reading
+		return m, nil, true
+	case key.Matches(k, m.keys.Up):
+		o.cursor--
+	case key.Matches(k, m.keys.Down):
+		o.cursor++
+	case key.Matches(k, m.keys.PageUp, m.keys.HalfPageUp):
+		o.cursor -= t.viewport.Height / 2
+	case key.Matches(k, m.keys.PageDown, m.keys.HalfPageDown):
+		o.cursor += t.viewport.Height / 2
+	case key.Matches(k, m.keys.Top):
+		o.cursor = 0
+	case key.Matches(k, m.keys.Bottom):
+		o.cursor = len(t.headings) - 1
+	default:
+		return m, nil, false
+	}
+
+	o.cursor = max(0, min(o.cursor, len(t.headings)-1))
+	if len(t.headings) > 0 && t.headings[o.cursor].row >= 0 {
+		offset := t.viewport.YOffset
+		t.viewport.SetYOffset(t.headings[o.cursor].row)
+		return m.syncScroll(m.activeSplit, t.viewport.YOffset-offset), nil, true
+	}
+	return m, nil, true
+}
+
+// toggleRaw switches a markdown tab between its rendering and its source
+func (m Model) toggleRaw() (tea.Model, tea.Cmd) {
+	if !m.isMarkdown() {
+		return m, nil
+	}
+	// Stay in the same section of the document
+	section := m.tab().currentHeading()
+	m.tab().raw = !m.tab().raw
+	m.tab().focusedLink = 0
+	updated, cmd := m.rerenderCurrentFile()
+	m = updated.(Model)
+	if section >= 0 && section < len(m.tab().headings) {
+		m.tab().viewport.SetYOffset(max(0, m.tab().headings[section].row))
+	}
+	return m, cmd
+}
+
+// cycleLink focuses the next or previous link of a rendered document, starting
+// from the top of the view when none is focused
+func (m Model) cycleLink(forward bool) Model {
+	t := m.tab()
+	var shown []int
+	for i, l := range t.links {
+		if l.row >= 0 {
+			shown = append(shown, i)
+		}
+	}
+	if len(shown) == 0 {
+		m.notice = "No links in this document"
+		return m
+	}
+
+	next := -1
+	if t.focusedLink == 0 {
+		// The first link in view, or the last one above it going back
+		for _, i := range shown {
+			if forward && t.links[i].row >= t.viewport.YOffset {
+				next = i
+				break
+			}
+			if !forward && t.links[i].row < t.viewport.YOffset+t.viewport.Height {
+				next = i
+			}
+		}
+	} else {
+		for j, i := range shown {
+			if i == t.focusedLink-1 {
+				step := 1
+				if !forward {
+					step = -1
+				}
+				next = shown[(j+step+len(shown))%len(shown)]
+			}
+		}
+	}
+	if next < 0 {
+		next = shown[0]
+		if !forward {
+			next = shown[len(shown)-1]
+		}
+	}
+
+	t.focusedLink = next + 1
+	offset := t.viewport.YOffset
+	scrollToRow(&t.viewport, t.links[next].row)
+	return m.syncScroll(m.activeSplit, t.viewport.YOffset-offset)
+}
+
+// followLink opens the focused link: a heading of the document, or another
+// file under the root with an optional heading. The jump list leads back.
+func (m Model) followLink() (tea.Model, tea.Cmd) {
+	t := m.tab()
+	link := t.links[t.focusedLink-1]
+	target, err := url.Parse(link.target)
+	if err != nil || target.Scheme != "" || target.Host != "" {
+		m.notice = "Not following external link " + link.target
+		return m, nil
+	}
+
+	m = m.recordJump()
+	if target.Path == "" {
+		return m.goToAnchor(target.Fragment), nil
+	}
+
+	// Relative to the document, or to the served directory for absolute links
+	base := filepath.Dir(t.path)
+	switch {
+	case filepath.IsAbs(target.Path):
+		base = m.rootDir
+	case t.path == StdinPath:
+		base = m.currentDir
+	}
+	path := filepath.Join(base, filepath.FromSlash(target.Path))
+	if !filebrowser.WithinRoot(path, m.rootDir) {
+		m.auditf("denied %s (outside root)", path)
+		m.notice = "Access denied: link leads outside the served directory"
+		return m, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		m.notice = fmt.Sprintf("Broken link %s: %v", link.target, err)
+		return m, nil
+	}
+
+	if info.IsDir() {
+		m.auditf("cd %s", filebrowser.DisplayPath(path, m.rootDir))
+		m.directoryHistory = append(m.directoryHistory, m.currentDir)
+		m.currentDir = path
+		m = m.focusPane(NavigatorPane)
+		m, cmd := m.refreshNavigator()
+		return m, tea.Batch(cmd, m.recordVisit(path, true))
+	}
+	m.auditf("open %s", filebrowser.DisplayPath(path, m.rootDir))
+	m = m.openTab(path)
+	m.tab().pendingAnchor = target.Fragment
+	updated, cmd := m.rerenderCurrentFile()
+	return updated, tea.Batch(cmd, m.recordVisit(path, false))
+}
+
+// goToAnchor scrolls the current tab to the heading with an anchor, or
+// remembers it until the tab is loaded
+func (m Model) goToAnchor(anchor string) Model {
+	t := m.tab()
+	if t.path != StdinPath && t.loadedAs != t.source() {
+		t.pendingAnchor = anchor
+		return m
+	}
+	t.pendingAnchor = ""
+	if anchor == "" {
+		return m
+	}
+
+	for _, h := range t.headings {
+		if h.anchor == strings.ToLower(anchor) && h.row >= 0 {
+			offset := t.viewport.YOffset
+			t.viewport.SetYOffset(h.row)
+			return m.syncScroll(m.activeSplit, t.viewport.YOffset-offset)
+		}
+	}
+	m.notice = "No heading #" + anchor
+	return m
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHeadings(t *testing.T) {
+	source := strings.Join([]string{
+		"# Getting *started*",
+		"",
+		"```sh",
+		"# not a heading",
+		"```",
+		"Setext title",
+		"------------",
+		"### Getting started ###",
+		"## Use [the API](api.md)",
+	}, "\n")
+
+	want := []heading{
+		{level: 1, text: "Getting started", anchor: "getting-started", line: 0},
+		{level: 2, text: "Setext title", anchor: "setext-title", line: 5},
+		{level: 3, text: "Getting started", anchor: "getting-started-1", line: 7},
+		{level: 2, text: "Use the API", anchor: "use-the-api", line: 8},
+	}
+	got := parseHeadings(source)
+	if len(got) != len(want) {
+		t.Fatalf("got %d headings, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("heading %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	links := parseLinks("![logo](logo.png) see [the guide](docs/guide.md \"Guide\") and [`x`](#x)\n```\n[code](no.md)\n```")
+	if len(links) != 2 || links[0].text != "the guide" || links[0].target != "docs/guide.md" || links[1].text != "x" || links[1].target != "#x" {
+		t.Errorf("links = %+v", links)
+	}
+}
+
+// openLinkedReadme rewrites README.md with links to a heading and another file, and opens it
+func openLinkedReadme(t *testing.T) Model {
+	t.Helper()
+	m := newTestModel(t, 80, 20)
+	readme := "# Project\n\nSee the [guide](docs/guide.md#guide) and [usage](#usage).\n\n" +
+		strings.Repeat("Filler paragraph.\n\n", 20) + "## Usage\n\n" + strings.Repeat("Run it.\n\n", 20)
+	if err := os.WriteFile(filepath.Join(m.currentDir, "README.md"), []byte(readme), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return send(t, m, keys("j", "j", "j", "enter")...)
+}
+
+func TestFollowLinks(t *testing.T) {
+	m := openLinkedReadme(t)
+	readme := m.tab().path
+
+	m = send(t, m, keys("}", "}")...)
+	if m.tab().focusedLink != 2 {
+		t.Fatalf("focused link %d, want 2", m.tab().focusedLink)
+	}
+	m = send(t, m, keys("enter")...)
+	usage := m.tab().headings[1]
+	if usage.anchor != "usage" || m.tab().viewport.YOffset != usage.row || usage.row == 0 {
+		t.Errorf("at row %d after following #usage, want %d", m.tab().viewport.YOffset, usage.row)
+	}
+	m = send(t, m, keys("ctrl+o")...)
+	if m.tab().viewport.YOffset != 0 {
+		t.Errorf("at row %d after jumping back, want 0", m.tab().viewport.YOffset)
+	}
+
+	m = send(t, m, keys("{", "enter")...) // Back to the guide
+	if want := filepath.Join(filepath.Dir(readme), "docs", "guide.md"); m.tab().path != want {
+		t.Fatalf("opened %s, want %s", m.tab().path, want)
+	}
+	if got := frame(m); !strings.Contains(got, "Read me.") {
+		t.Errorf("guide not shown:\n%s", got)
+	}
+	m = send(t, m, keys("ctrl+o")...)
+	if m.tab().path != readme {
+		t.Errorf("jumped back to %s, want %s", m.tab().path, readme)
+	}
+	assertFits(t, m, 80, 20)
+}
+
+func TestOutlineSidebar(t *testing.T) {
+	m := openLinkedReadme(t)
+	width := m.layout.ViewportWidth
+
+	m = send(t, m, keys("o")...)
+	if m.layout.ViewportWidth >= width {
+		t.Errorf("viewport width %d with the outline, want less than %d", m.layout.ViewportWidth, width)
+	}
+	got := frame(m)
+	if !strings.Contains(got, "│ Project") || !strings.Contains(got, "│   Usage") {
+		t.Errorf("outline not shown:\n%s", got)
+	}
+	assertFits(t, m, 80, 20)
+
+	m = send(t, m, keys("j", "enter")...)
+	if usage := m.tab().headings[1]; m.tab().viewport.YOffset != usage.row || m.tab().outline.focused {
+		t.Errorf("at row %d, outline focused %v; want row %d, reading", m.tab().viewport.YOffset, m.tab().outline.focused, usage.row)
+	}
+
+	m = send(t, m, keys("o", "o")...) // Focus, then close
+	if m.tab().outline != nil || m.layout.ViewportWidth != width {
+		t.Error("outline not closed")
+	}
+}
+
+func TestToggleRawMarkdown(t *testing.T) {
+	m := openLinkedReadme(t)
+	m = send(t, m, keys("R")...)
+	if got := frame(m); !strings.Contains(got, "1 │ # Project") {
+		t.Errorf("source not shown:\n%s", got)
+	}
+
+	// Headings map to source lines, so the outline works on the source too
+	m = send(t, m, keys("o", "G")...)
+	if row := m.tab().viewport.YOffset; row != m.tab().headings[1].row {
+		t.Errorf("at row %d, want the source line of Usage at %d", row, m.tab().headings[1].row)
+	}
+	m = send(t, m, keys("esc", "R")...)
+	if got := frame(m); strings.Contains(got, "# Project") {
+		t.Errorf("still showing the source:\n%s", got)
+	}
+}
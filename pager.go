@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// StdinPath is the tab path standing for content piped in on stdin
+const StdinPath = "-"
+
+const (
+	// stdinChunkSize is how much of stdin is read at a time
+	stdinChunkSize = 64 * 1024
+	// stdinRenderInterval is how often the view catches up with stdin while it
+	// streams, since each render lays out everything kept so far
+	stdinRenderInterval = 100 * time.Millisecond
+)
+
+// pagerInput is the content piped in on stdin, shared by every tab showing it
+type pagerInput struct {
+	reader        io.Reader
+	data          []byte
+	done          bool
+	trimmed       bool // Lines were dropped from the start to stay within MaxFileSize
+	renderPending bool // A stdinRenderMsg is on its way
+}
+
+// stdinMsg delivers the next chunk read from stdin
+type stdinMsg struct {
+	data []byte
+	err  error // io.EOF once stdin is closed
+}
+
+// stdinRenderMsg is the tick on which the view shows what stdin has delivered since the last one
+type stdinRenderMsg struct{}
+
+// trim drops whole lines from the start once more than MaxFileSize is kept, so
+// an endless stream such as a log is followed in bounded memory
+func (p *pagerInput) trim() {
+	excess := len(p.data) - MaxFileSize
+	if excess <= 0 {
+		return
+	}
+	if i := bytes.IndexByte(p.data[excess:], '\n'); i >= 0 {
+		excess += i + 1
+	}
+	p.data = p.data[excess:]
+	p.trimmed = true
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// readStdinCmd reads the next chunk of stdin in the background
+func readStdinCmd(r io.Reader) tea.Cmd {
+	return func() tea.Msg {
+		buf := make([]byte, stdinChunkSize)
+		n, err := r.Read(buf)
+		return stdinMsg{data: buf[:n], err: err}
+	}
+}
+
+// openStdin shows stdin in the content pane with the navigator hidden, like a pager
+func (m Model) openStdin(r io.Reader) Model {
+	m.stdin = &pagerInput{reader: r}
+	m.navigatorCollapsed = true
+	m.statePath = "" // Hiding the navigator here is not the user's preference
+	return m.openTab(StdinPath).focusSplit(m.activeSplit)
+}
+
+// handleStdin keeps a chunk of stdin and asks for the next one, showing it on
+// the next stdinRenderMsg, or at once when stdin has ended
+func (m Model) handleStdin(msg stdinMsg) (tea.Model, tea.Cmd) {
+	m.stdin.data = append(m.stdin.data, msg.data...)
+	m.stdin.trim()
+
+	switch {
+	case msg.err == nil:
+		cmds := []tea.Cmd{readStdinCmd(m.stdin.reader)}
+		if !m.stdin.renderPending {
+			m.stdin.renderPending = true
+			cmds = append(cmds, tea.Tick(stdinRenderInterval, func(time.Time) tea.Msg {
+				return stdinRenderMsg{}
+			}))
+		}
+		return m, tea.Batch(cmds...)
+	case !errors.Is(msg.err, io.EOF):
+		m.notice = "Error reading stdin: " + msg.err.Error()
+	}
+	m.stdin.done = true
+	return m.renderStdin()
+}
+
+// renderStdin re-renders the tabs showing stdin. A tab scrolled to the bottom
+// of overflowing content follows the new lines.
+func (m Model) renderStdin() (tea.Model, tea.Cmd) {
+	m.stdin.renderPending = false
+	var cmds []tea.Cmd
+	for i, s := range m.splits {
+		t := s.tab()
+		if t.path != StdinPath {
+			continue
+		}
+		following := t.viewport.TotalLineCount() > t.viewport.Height && t.viewport.AtBottom()
+		updated, cmd := m.rerenderSplit(i)
+		m = updated.(Model)
+		cmds = append(cmds, cmd)
+		if following {
+			t.viewport.GotoBottom()
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// Markdown sniffing patterns
+var (
+	markdownHeadingPattern = regexp.MustCompile(`^#{1,6} \S`)
+	markdownFencePattern   = regexp.MustCompile("^(```|~~~)")
+	markdownLinkPattern    = regexp.MustCompile(`\[[^\]]+\]\([^)\s]+\)`)
+	markdownListPattern    = regexp.MustCompile(`^\s*([-*+]|\d+\.) \S`)
+)
+
+// looksLikeMarkdown guesses from the first lines of content whether it is markdown,
+// for content without a file name such as stdin
+func looksLikeMarkdown(content string) bool {
+	const sniffLines = 50
+	if strings.HasPrefix(content, "#!") {
+		return false // A script whose comments would pass for headings
+	}
+
+	lines := strings.SplitN(content, "\n", sniffLines+1)
+	lines = lines[:min(len(lines), sniffLines)]
+
+	score := 0
+	for i, line := range lines {
+		// A heading must stand apart from the next line, unlike a run of comments
+		if markdownHeadingPattern.MatchString(line) && (i+1 == len(lines) || strings.TrimSpace(lines[i+1]) == "") {
+			score += 2
+		}
+		if markdownFencePattern.MatchString(line) {
+			score++
+		}
+		if markdownLinkPattern.MatchString(line) {
+			score++
+		}
+		if markdownListPattern.MatchString(line) {
+			score++
+		}
+	}
+	return score >= 3
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStdinRendersOnATick(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = m.openStdin(strings.NewReader(""))
+	markdown := "# Title\n\nSome [link](https://example.com).\n\n- one\n- two\n"
+
+	// Chunks are kept as they come, and shown as source until stdin ends
+	updated, _ := m.Update(stdinMsg{data: []byte(markdown[:10])})
+	updated, _ = updated.(Model).Update(stdinMsg{data: []byte(markdown[10:])})
+	m = updated.(Model)
+	if strings.Contains(m.tab().content, "Some") {
+		t.Errorf("stdin shown before the render tick:\n%s", m.tab().content)
+	}
+	m = send(t, m, stdinRenderMsg{})
+	if !strings.Contains(m.tab().content, "# Title") {
+		t.Errorf("streaming markdown not shown as source:\n%s", m.tab().content)
+	}
+
+	m = send(t, m, stdinMsg{err: io.EOF})
+	if !m.stdin.done || strings.Contains(m.tab().content, "# Title") || !strings.Contains(m.tab().content, "Title") {
+		t.Errorf("markdown not rendered once stdin ended:\n%s", m.tab().content)
+	}
+}
+
+func TestStdinKeepsItsTail(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	p := &pagerInput{data: []byte(strings.Repeat(line, MaxFileSize/len(line)+1) + "last\n")}
+	p.trim()
+	if len(p.data) > MaxFileSize || !p.trimmed {
+		t.Fatalf("kept %d bytes, trimmed %v, with a limit of %d", len(p.data), p.trimmed, MaxFileSize)
+	}
+	if !bytes.HasPrefix(p.data, []byte(line)) || !bytes.HasSuffix(p.data, []byte("last\n")) {
+		t.Error("trimming did not keep whole lines up to the end")
+	}
+
+	short := &pagerInput{data: []byte("short\n")}
+	short.trim()
+	if string(short.data) != "short\n" || short.trimmed {
+		t.Errorf("trimmed input within the limit: %q", short.data)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// picker is the state of picker mode, where choosing entries in the navigator
+// prints their paths and exits instead of opening them
+type picker struct {
+	dirsOnly bool
+	multi    bool
+	selected []string // Entries toggled so far in multi mode, in order
+	result   []string // The confirmed pick, nil while picking or when cancelled
+}
+
+// parseExtensions splits a comma-separated extension list, adding missing dots
+func parseExtensions(list string) []string {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, strings.ToLower(ext))
+	}
+	return exts
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than captured by a pipe or file
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// startPicking puts the model in picker mode, listing only what can be picked
+// besides the directories leading to it
+func (m Model) startPicking(dirsOnly, multi bool, extensions []string) Model {
+	m.picker = &picker{dirsOnly: dirsOnly, multi: multi}
+	m.listing.DirsOnly = dirsOnly
+	m.listing.Extensions = extensions
+	return m.listNow()
+}
+
+// pickable reports whether an entry can be picked: directories in dirs-only mode, files otherwise
+func (p *picker) pickable(item FileItem) bool {
+	return item.name != ".." && item.isDir == p.dirsOnly
+}
+
+// isPicked reports whether path has been toggled in multi mode
+func (p *picker) isPicked(path string) bool {
+	for _, picked := range p.selected {
+		if picked == path {
+			return true
+		}
+	}
+	return false
+}
+
+// markPicked flags the items toggled in multi mode so the navigator can show them
+func (m Model) markPicked(items []list.Item) []list.Item {
+	if m.picker == nil {
+		return items
+	}
+	marked := make([]list.Item, len(items))
+	for i, item := range items {
+		fileItem := item.(FileItem)
+		fileItem.picked = m.picker.isPicked(fileItem.path)
+		marked[i] = fileItem
+	}
+	return marked
+}
+
+// handlePick confirms or toggles picks from the navigator, reporting whether
+// the key was used; other keys, such as opening a directory, work as usual
+func (m Model) handlePick(k keyPress) (tea.Model, tea.Cmd, bool) {
+	if m.picker == nil || m.focusedPane != NavigatorPane {
+		return m, nil, false
+	}
+	item, ok := m.list.SelectedItem().(FileItem)
+	if !ok {
+		return m, nil, false
+	}
+
+	switch {
+	case key.Matches(k, m.keys.Open):
+		if m.picker.multi && len(m.picker.selected) > 0 {
+			m.picker.result = m.picker.selected
+			return m, tea.Quit, true
+		}
+		if m.picker.pickable(item) {
+			m.picker.result = []string{item.path}
+			return m, tea.Quit, true
+		}
+	case key.Matches(k, m.keys.Mark) && m.picker.multi:
+		if !m.picker.pickable(item) {
+			return m, nil, true
+		}
+		if m.picker.isPicked(item.path) {
+			selected := m.picker.selected[:0:0]
+			for _, path := range m.picker.selected {
+				if path != item.path {
+					selected = append(selected, path)
+				}
+			}
+			m.picker.selected = selected
+		} else {
+			m.picker.selected = append(m.picker.selected, item.path)
+		}
+		m.list.SetItems(m.markPicked(m.list.Items()))
+		m.list.CursorDown() // Ready to toggle the next entry
+		updated, cmd := m.updatePreview()
+		return updated, cmd, true
+	}
+	return m, nil, false
+}
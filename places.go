@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// Frecency aging, as zoxide does it: once the ranks add up to more than
+// maxTotalRank, every rank shrinks and the ones falling below one are forgotten
+const (
+	maxTotalRank = 10000
+	agingFactor  = 0.9
+)
+
+// visit is how often and how lately a directory or file was visited
+type visit struct {
+	Rank float64   `json:"rank"`
+	Last time.Time `json:"last"`
+	Dir  bool      `json:"dir,omitempty"`
+}
+
+// frecency weighs the rank of a visit by how recent it is
+func (v visit) frecency(now time.Time) float64 {
+	switch age := now.Sub(v.Last); {
+	case age < time.Hour:
+		return v.Rank * 4
+	case age < 24*time.Hour:
+		return v.Rank * 2
+	case age < 7*24*time.Hour:
+		return v.Rank / 2
+	}
+	return v.Rank / 4
+}
+
+// places are the bookmarks and visited paths remembered between runs
+type places struct {
+	Bookmarks map[string]string `json:"bookmarks,omitempty"` // Paths by letter
+	Visits    map[string]*visit `json:"visits,omitempty"`    // By path
+
+	path string // Where they are saved, empty to keep them for the session only
+}
+
+// newPlaces creates empty places saved at path
+func newPlaces(path string) *places {
+	return &places{Bookmarks: make(map[string]string), Visits: make(map[string]*visit), path: path}
+}
+
+// defaultPlacesPath returns the places file under $XDG_DATA_HOME, falling back
+// to ~/.local/share, or "" when neither can be determined
+func defaultPlacesPath() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "bubbletest", "places.json")
+}
+
+// loadPlaces reads the places file, starting afresh if it is missing or unreadable
+func loadPlaces(path string) *places {
+	p := newPlaces(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		logger.Warn("ignoring unreadable places file", "path", path, "err", err)
+		return newPlaces(path)
+	}
+	if p.Bookmarks == nil {
+		p.Bookmarks = make(map[string]string)
+	}
+	if p.Visits == nil {
+		p.Visits = make(map[string]*visit)
+	}
+	return p
+}
+
+// saveCmd writes the places file off the UI goroutine. They are encoded here,
+// as the UI goes on changing them meanwhile.
+func (p *places) saveCmd() tea.Cmd {
+	if p.path == "" {
+		return nil
+	}
+	path := p.path
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		logger.Warn("encoding places failed", "err", err)
+		return nil
+	}
+	return func() tea.Msg {
+		if err := writeFileAtomic(path, data); err != nil {
+			logger.Warn("saving places failed", "path", path, "err", err)
+		}
+		return nil
+	}
+}
+
+// writeFileAtomic writes a temporary file and renames it to path, so writes
+// running side by side, or cut short, never leave a mix behind
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// visit counts a visit to path, aging every rank once they add up to too much
+func (p *places) visit(path string, dir bool, now time.Time) {
+	v, ok := p.Visits[path]
+	if !ok {
+		v = &visit{}
+		p.Visits[path] = v
+	}
+	v.Rank++
+	v.Last = now
+	v.Dir = dir
+
+	total := 0.0
+	for _, v := range p.Visits {
+		total += v.Rank
+	}
+	if total <= maxTotalRank {
+		return
+	}
+	for path, v := range p.Visits {
+		v.Rank *= agingFactor * maxTotalRank / total
+		if v.Rank < 1 {
+			delete(p.Visits, path)
+		}
+	}
+}
+
+// query lists the visited paths matching words, most frecent first
+func (p *places) query(words []string, now time.Time) []string {
+	var paths []string
+	for path := range p.Visits {
+		if matchesQuery(path, words) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := p.Visits[paths[i]].frecency(now), p.Visits[paths[j]].frecency(now)
+		if a != b {
+			return a > b
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// matchesQuery reports whether words appear in path in order, the last of them
+// in its final element, like zoxide. Words in lower case match either case.
+func matchesQuery(path string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	if !strings.ContainsFunc(strings.Join(words, ""), unicode.IsUpper) {
+		path = strings.ToLower(path)
+	}
+
+	pos := 0
+	for _, word := range words[:len(words)-1] {
+		i := strings.Index(path[pos:], word)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(word)
+	}
+	last := words[len(words)-1]
+	base := strings.LastIndex(strings.TrimRight(path, string(filepath.Separator)), string(filepath.Separator)) + 1
+	return strings.LastIndex(path, last) >= max(pos, base)
+}
+
+// bookmarkLetters lists the letters bookmarks are set for, in order
+func (p *places) bookmarkLetters() []string {
+	letters := make([]string, 0, len(p.Bookmarks))
+	for letter := range p.Bookmarks {
+		letters = append(letters, letter)
+	}
+	sort.Strings(letters)
+	return letters
+}
+
+// recordVisit counts a visit to a directory or file for jumping back to it
+func (m Model) recordVisit(path string, dir bool) tea.Cmd {
+	m.places.visit(path, dir, time.Now())
+	return m.places.saveCmd()
+}
+
+// handleBookmarkName reads the letter naming the bookmark to set or jump to
+func (m Model) handleBookmarkName(op markOp, name string) (tea.Model, tea.Cmd) {
+	if op == markBookmark {
+		item, ok := m.list.SelectedItem().(FileItem)
+		if !ok || item.name == ".." {
+			return m, nil
+		}
+		m.places.Bookmarks[name] = item.path
+		m.notice = fmt.Sprintf("Bookmark %s set to %s", name, filebrowser.DisplayPath(item.path, m.rootDir))
+		return m, m.places.saveCmd()
+	}
+
+	path, ok := m.places.Bookmarks[name]
+	if !ok {
+		m.notice = fmt.Sprintf("Bookmark %s is not set", name)
+		return m, nil
+	}
+	return m.jumpToPlace(path)
+}
+
+// jumpToPlace shows a bookmarked or visited path: a directory in the
+// navigator, a file in the active split with its directory listed
+func (m Model) jumpToPlace(path string) (tea.Model, tea.Cmd) {
+	if !filebrowser.WithinRoot(path, m.rootDir) {
+		m.auditf("denied %s (outside root)", path)
+		m.notice = "Access denied: path is outside the served directory"
+		return m, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		m.notice = fmt.Sprintf("Cannot jump to %s: %v", filebrowser.DisplayPath(path, m.rootDir), err)
+		return m, nil
+	}
+
+	m = m.recordJump()
+	dir, selected := path, ""
+	if !info.IsDir() {
+		dir, selected = filepath.Dir(path), path
+	}
+	cmds := []tea.Cmd{m.recordVisit(path, info.IsDir())}
+	if dir != m.currentDir || m.bookmarkList {
+		if dir != m.currentDir {
+			m.auditf("cd %s", filebrowser.DisplayPath(dir, m.rootDir))
+			m.directoryHistory = append(m.directoryHistory, m.currentDir)
+		}
+		m.currentDir = dir
+		m.selectOnListing = selected
+		var cmd tea.Cmd
+		m, cmd = m.refreshNavigator()
+		cmds = append(cmds, cmd)
+	}
+	if info.IsDir() {
+		return m.focusPane(NavigatorPane), tea.Batch(cmds...)
+	}
+
+	m.auditf("open %s", filebrowser.DisplayPath(path, m.rootDir))
+	m = m.openTab(path)
+	m.tab().stale = true
+	m = m.focusSplit(m.activeSplit)
+	updated, cmd := m.rerenderCurrentFile()
+	return updated, tea.Batch(append(cmds, cmd)...)
+}
+
+// promptJump asks for words to find a visited directory or file by, jumping
+// to the most frecent match
+func (m Model) promptJump() (tea.Model, tea.Cmd) {
+	return m.openPrompt("Jump to:", "", func(m Model, value string) (tea.Model, tea.Cmd) {
+		words := strings.Fields(value)
+		if len(words) == 0 {
+			return m, nil
+		}
+		for _, path := range m.places.query(words, time.Now()) {
+			if path == m.currentDir || path == m.tab().path || !filebrowser.WithinRoot(path, m.rootDir) {
+				continue // Already here, or out of reach
+			}
+			if _, err := os.Stat(path); err != nil {
+				delete(m.places.Visits, path) // Gone since, as zoxide forgets them
+				continue
+			}
+			return m.jumpToPlace(path)
+		}
+		m.notice = fmt.Sprintf("Nothing visited matches %q", value)
+		return m, nil
+	})
+}
+
+// bookmarkItems lists the bookmarks as navigator entries, by letter
+func (m Model) bookmarkItems() []list.Item {
+	var items []list.Item
+	for _, letter := range m.places.bookmarkLetters() {
+		path := m.places.Bookmarks[letter]
+		info, err := os.Stat(path)
+		items = append(items, FileItem{
+			name:     letter + "  " + filepath.Base(path),
+			path:     path,
+			isDir:    err == nil && info.IsDir(),
+			bookmark: letter,
+			location: filebrowser.DisplayPath(filepath.Dir(path), m.rootDir),
+		})
+	}
+	return items
+}
+
+// showBookmarks lists the bookmarks in place of the navigator's directory
+func (m Model) showBookmarks() (tea.Model, tea.Cmd) {
+	if len(m.places.Bookmarks) == 0 {
+		m.notice = "No bookmarks yet: select an entry and press " + m.keys.Bookmark.Help().Key + " and a letter"
+		return m, nil
+	}
+	m.bookmarkList = true
+	m.list.SetItems(m.bookmarkItems())
+	m.list.Select(0)
+	m.list.Title = "Bookmarks"
+	return m.focusPane(NavigatorPane), nil
+}
+
+// handleBookmarkList jumps to and removes bookmarks while they are listed,
+// reporting whether the key was used
+func (m Model) handleBookmarkList(k keyPress) (tea.Model, tea.Cmd, bool) {
+	if !m.bookmarkList || m.focusedPane != NavigatorPane {
+		return m, nil, false
+	}
+	item, _ := m.list.SelectedItem().(FileItem)
+
+	switch {
+	case key.Matches(k, m.keys.Open, m.keys.Right):
+		if item.path == "" {
+			return m, nil, true
+		}
+		updated, cmd := m.jumpToPlace(item.path)
+		return updated, cmd, true
+	case key.Matches(k, m.keys.RemoveBookmark):
+		if item.bookmark == "" {
+			return m, nil, true
+		}
+		delete(m.places.Bookmarks, item.bookmark)
+		m.notice = "Bookmark " + item.bookmark + " removed"
+		index := m.list.Index()
+		m.list.SetItems(m.bookmarkItems())
+		m.list.Select(min(index, len(m.list.Items())-1))
+		if len(m.list.Items()) > 0 {
+			return m, m.places.saveCmd(), true
+		}
+		updated, cmd := m.closeBookmarks()
+		return updated, tea.Batch(m.places.saveCmd(), cmd), true
+	case key.Matches(k, m.keys.Left, m.keys.Back, m.keys.Bookmarks, m.keys.PaneSelection):
+		updated, cmd := m.closeBookmarks()
+		return updated, cmd, true
+	}
+	return m, nil, false
+}
+
+// closeBookmarks lists the current directory again
+func (m Model) closeBookmarks() (Model, tea.Cmd) {
+	m.bookmarkList = false
+	return m.refreshNavigator()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchesQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"lib", true},
+		{"src lib", true},
+		{"SRC", false}, // Upper case only matches upper case
+		{"Project lib", true},
+		{"lib src", false}, // Out of order
+		{"src", false},     // Not in the final element
+		{"pro li", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := matchesQuery("/home/me/Project/src/lib", strings.Fields(tt.query)); got != tt.want {
+			t.Errorf("matchesQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFrecencyRanksAndAges(t *testing.T) {
+	now := time.Now()
+	p := newPlaces("")
+	for range 5 {
+		p.visit("/old/docs", true, now.Add(-30*24*time.Hour))
+	}
+	p.visit("/new/docs", true, now)
+	if got := p.query([]string{"docs"}, now); len(got) != 2 || got[0] != "/new/docs" {
+		t.Errorf("query = %v, want the recent visit first", got)
+	}
+
+	// Past the total rank, every rank shrinks and the rarely visited are forgotten
+	p.Visits["/often"] = &visit{Rank: maxTotalRank, Last: now}
+	p.visit("/often", true, now)
+	if _, ok := p.Visits["/new/docs"]; ok {
+		t.Error("a single visit survived aging")
+	}
+	if rank := p.Visits["/often"].Rank; rank >= maxTotalRank {
+		t.Errorf("rank = %v after aging, want below %d", rank, maxTotalRank)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	project := m.currentDir
+	docs := filepath.Join(project, "docs")
+	notes := filepath.Join(project, "notes.txt")
+
+	m = send(t, m, keys("j", "b", "a", "j", "j", "j", "j", "b", "n")...)
+	if m.places.Bookmarks["a"] != docs || m.places.Bookmarks["n"] != notes {
+		t.Fatalf("bookmarks = %v", m.places.Bookmarks)
+	}
+	if saved := loadPlaces(defaultPlacesPath()); saved.Bookmarks["a"] != docs {
+		t.Errorf("saved bookmarks = %v", saved.Bookmarks)
+	}
+
+	// Jumping goes through the directory history, so back returns
+	m = send(t, m, keys("`", "a")...)
+	if m.currentDir != docs {
+		t.Fatalf("current dir = %s after jumping to bookmark a", m.currentDir)
+	}
+	m = send(t, m, keys("z")...)
+	if m.currentDir != project {
+		t.Errorf("current dir = %s after going back, want %s", m.currentDir, project)
+	}
+
+	m = send(t, m, keys("B")...)
+	got := frame(m)
+	if !strings.Contains(got, "Bookmarks") || !strings.Contains(got, "a  docs") || !strings.Contains(got, "n  notes.txt") || !strings.Contains(got, "~/project") {
+		t.Errorf("bookmarks not listed:\n%s", got)
+	}
+	assertFits(t, m, 80, 20)
+	m = send(t, m, keys("j", "enter")...)
+	if m.tab().path != notes || m.currentDir != project || m.bookmarkList {
+		t.Errorf("showing %s in %s after opening bookmark n", m.tab().path, m.currentDir)
+	}
+
+	m = send(t, m, keys("left", "B", "x")...)
+	if _, ok := m.places.Bookmarks["a"]; ok || len(m.list.Items()) != 1 {
+		t.Errorf("bookmark a not removed: %v", m.places.Bookmarks)
+	}
+	m = send(t, m, keys("x")...)
+	if m.bookmarkList || len(m.places.Bookmarks) != 0 {
+		t.Errorf("list still shown after removing the last bookmark: %v", m.places.Bookmarks)
+	}
+	if !strings.Contains(frame(m), "notes.txt") {
+		t.Errorf("directory not listed again:\n%s", frame(m))
+	}
+}
+
+func TestJumpPromptFindsVisitedPlaces(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	project := m.currentDir
+	m = send(t, m, keys("j", "j", "enter", "j", "enter")...) // src/lib
+	m = send(t, m, keys("z", "z")...)
+	if m.currentDir != project {
+		t.Fatalf("current dir = %s, want %s", m.currentDir, project)
+	}
+
+	m = send(t, m, keys("J", "l", "i", "enter")...)
+	if want := filepath.Join(project, "src", "lib"); m.currentDir != want {
+		t.Errorf("current dir = %s after jumping, want %s", m.currentDir, want)
+	}
+	m = send(t, m, keys("z")...)
+	if m.currentDir != project {
+		t.Errorf("back did not return from the jump: %s", m.currentDir)
+	}
+
+	m = send(t, m, keys("J", "q", "enter")...)
+	if got := frame(m); !strings.Contains(got, `Nothing visited matches "q"`) {
+		t.Errorf("no notice for a query without matches:\n%s", got)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// promptSubmitFunc handles the value entered into the prompt
+type promptSubmitFunc func(m Model, value string) (tea.Model, tea.Cmd)
+
+// openPrompt shows a single-line input in place of the help text
+func (m Model) openPrompt(label, initial string, submit promptSubmitFunc) (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.Prompt = " " + label + " "
+	input.SetValue(initial)
+	input.CursorEnd()
+
+	m.prompt = input
+	m.promptSubmit = submit
+	return m, m.prompt.Focus()
+}
+
+// handlePrompt routes keys to the open prompt
+func (m Model) handlePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.promptSubmit = nil
+		return m, nil
+	case "enter":
+		submit := m.promptSubmit
+		m.promptSubmit = nil
+		return submit(m, m.prompt.Value())
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+// promptActive reports whether the prompt is capturing keys
+func (m Model) promptActive() bool {
+	return m.promptSubmit != nil
+}
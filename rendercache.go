@@ -0,0 +1,145 @@
+package main
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+)
+
+const (
+	// DefaultRenderCacheMB is how much rendered output is kept by default, in megabytes
+	DefaultRenderCacheMB = 32
+	// maxMarkdownRenderers bounds the glamour renderers kept, one per style and width
+	maxMarkdownRenderers = 8
+)
+
+// renderKey identifies one rendering of a file: the version of the file and
+// every setting the output depends on
+type renderKey struct {
+	path     string
+	revision string
+	modTime  time.Time // Zero for revisions, which do not change
+	size     int64
+
+	width         int
+	markdownWidth int
+	lineNumbers   bool
+	raw           bool
+	wrap          WrapMode
+	markdownStyle string
+	syntaxStyle   string
+}
+
+// markdownKey identifies a glamour renderer
+type markdownKey struct {
+	style  string
+	syntax string
+	width  int
+}
+
+// renderCache keeps recently rendered files, dropping the least recently used
+// once their total size passes maxBytes, and reuses glamour renderers since
+// building one is slow. It is shared by every copy of the model.
+type renderCache struct {
+	maxBytes int
+	used     int
+	order    *list.List // Of *renderEntry, most recently used first
+	entries  map[renderKey]*list.Element
+	markdown map[markdownKey]*glamour.TermRenderer
+}
+
+type renderEntry struct {
+	key      renderKey
+	rendered string
+}
+
+// newRenderCache creates a cache holding up to maxBytes of rendered output, none when zero
+func newRenderCache(maxBytes int) *renderCache {
+	return &renderCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[renderKey]*list.Element),
+		markdown: make(map[markdownKey]*glamour.TermRenderer),
+	}
+}
+
+// get returns the rendering stored for key, marking it recently used
+func (c *renderCache) get(key renderKey) (string, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*renderEntry).rendered, true
+}
+
+// put stores a rendering, evicting the least recently used ones to make room.
+// Renderings larger than the whole cache are not kept.
+func (c *renderCache) put(key renderKey, rendered string) {
+	if len(rendered) > c.maxBytes {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, rendered: rendered})
+	c.used += len(rendered)
+	for c.used > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *renderCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*renderEntry)
+	delete(c.entries, entry.key)
+	c.used -= len(entry.rendered)
+}
+
+// renderFileCached renders the current tab like renderFile, reusing an earlier
+// rendering of the same version of the file with the same settings
+func (m Model) renderFileCached() string {
+	t := m.tab()
+	if t.path == StdinPath {
+		return m.renderFile() // Still growing, so never the same twice
+	}
+
+	key := renderKey{
+		path:          t.path,
+		revision:      t.revision,
+		modTime:       t.modTime,
+		size:          int64(len(t.data)),
+		width:         m.layout.ViewportWidth,
+		markdownWidth: m.markdownWidth,
+		lineNumbers:   t.showLineNumbers,
+		raw:           t.raw,
+		wrap:          m.wrapMode,
+		markdownStyle: m.theme.MarkdownStyle,
+		syntaxStyle:   m.theme.SyntaxHighlight,
+	}
+	if rendered, ok := m.renderCache.get(key); ok {
+		return rendered
+	}
+	rendered := m.renderFile()
+	m.renderCache.put(key, rendered)
+	return rendered
+}
+
+// markdownRenderer returns a glamour renderer for the theme at width, reusing
+// one built before
+func (c *renderCache) markdownRenderer(t Theme, width int) (*glamour.TermRenderer, error) {
+	key := markdownKey{style: t.MarkdownStyle, syntax: t.SyntaxHighlight, width: width}
+	if renderer, ok := c.markdown[key]; ok {
+		return renderer, nil
+	}
+
+	renderer, err := glamour.NewTermRenderer(t.markdownOption(), glamour.WithWordWrap(width))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.markdown) >= maxMarkdownRenderers {
+		clear(c.markdown) // Widths seen while dragging a divider are rarely needed again
+	}
+	c.markdown[key] = renderer
+	return renderer, nil
+}
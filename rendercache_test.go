@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRenderCache(10)
+	a, b, d := renderKey{path: "a"}, renderKey{path: "b"}, renderKey{path: "d"}
+
+	c.put(a, "aaaa")
+	c.put(b, "bbbb")
+	c.get(a) // a is now more recent than b
+	c.put(d, "dddd")
+
+	if _, ok := c.get(b); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []renderKey{a, d} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("%s should be kept", key.path)
+		}
+	}
+	if c.used != 8 {
+		t.Errorf("used = %d, want 8", c.used)
+	}
+
+	c.put(renderKey{path: "huge"}, strings.Repeat("x", 11))
+	if _, ok := c.get(renderKey{path: "huge"}); ok || c.used != 8 {
+		t.Error("a rendering larger than the cache was kept")
+	}
+}
+
+func TestRenderCacheReusedAcrossFullscreen(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "enter")...) // README.md
+	normal := m.tab().content
+
+	m = send(t, m, keys("f")...)
+	if len(m.renderCache.entries) != 2 {
+		t.Fatalf("want a rendering per width, have %d", len(m.renderCache.entries))
+	}
+	m = send(t, m, keys("f")...)
+	if len(m.renderCache.entries) != 2 || m.tab().content != normal {
+		t.Error("leaving fullscreen did not reuse the first rendering")
+	}
+
+	// A changed file is rendered afresh when read again
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(m.tab().path, []byte("# Changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(m.tab().path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	m = send(t, m, keys("left", "enter")...)
+	if !strings.Contains(m.tab().content, "Changed") {
+		t.Errorf("stale rendering shown after the file changed:\n%s", m.tab().content)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Navigator resizing limits
+const (
+	MinLeftPaneWidth = 12 // Narrowest rendered navigator when resized
+	MinContentWidth  = 20 // Space always left for the content splits
+	ResizeStep       = 2  // Columns moved per resize key press
+)
+
+// uiState is the view state remembered between runs
+type uiState struct {
+	NavigatorRatio     float64 `json:"navigator_ratio,omitempty"`
+	NavigatorCollapsed bool    `json:"navigator_collapsed,omitempty"`
+}
+
+// defaultStatePath returns the state file under $XDG_STATE_HOME, falling back
+// to ~/.local/state, or "" when neither can be determined
+func defaultStatePath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "state.json")
+}
+
+// loadState reads the state file, returning the zero state if it is missing or unreadable
+func loadState(path string) uiState {
+	var state uiState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Warn("ignoring unreadable state file", "path", path, "err", err)
+	}
+	return state
+}
+
+// saveStateCmd writes the pane arrangement to the state file off the UI goroutine
+func (m Model) saveStateCmd() tea.Cmd {
+	if m.statePath == "" {
+		return nil
+	}
+	path := m.statePath
+	state := uiState{NavigatorRatio: m.navigatorRatio, NavigatorCollapsed: m.navigatorCollapsed}
+	return func() tea.Msg {
+		// Saves overlap while the divider is dragged, so never leave a partial file
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err == nil {
+			err = writeFileAtomic(path, data)
+		}
+		if err != nil {
+			logger.Warn("saving state failed", "path", path, "err", err)
+		}
+		return nil
+	}
+}
+
+// navigatorRenderedWidth returns the navigator's width including its border,
+// given how much of the terminal the parent column already takes
+func (m Model) navigatorRenderedWidth(parentWidth int) int {
+	if m.navigatorCollapsed {
+		return 0
+	}
+	if m.navigatorRatio == 0 {
+		maxWidth := m.maxNavigatorWidth
+		if maxWidth == 0 {
+			maxWidth = MaxLeftPaneWidth
+		}
+		return min(maxWidth, m.width/4)
+	}
+
+	width := int(math.Round(m.navigatorRatio * float64(m.width)))
+	width = min(width, m.width-parentWidth-MinContentWidth)
+	return max(width, MinLeftPaneWidth)
+}
+
+// resizeNavigator moves the divider between the navigator and the content by
+// delta columns, keeping the result as a ratio of the terminal width
+func (m Model) resizeNavigator(delta int) Model {
+	if m.width == 0 || m.isFullscreen {
+		return m
+	}
+	if m.navigatorCollapsed {
+		// Bring the navigator back at its previous width first
+		m.navigatorCollapsed = false
+		return m
+	}
+
+	current := m.layout.LeftPaneWidth + BorderWidth
+	m.navigatorRatio = float64(current+delta) / float64(m.width)
+
+	// Store the clamped width so resizing past a limit does not build up slack
+	m.navigatorRatio = float64(m.navigatorRenderedWidth(m.parentPaneRenderedWidth())) / float64(m.width)
+	return m
+}
+
+// setNavigatorCollapsed hides or shows the navigator, moving focus out of it when hidden
+func (m Model) setNavigatorCollapsed(collapsed bool) Model {
+	if m.isFullscreen {
+		return m
+	}
+	m.navigatorCollapsed = collapsed
+	if collapsed && m.focusedPane == NavigatorPane {
+		m = m.focusSplit(m.activeSplit)
+	}
+	return m
+}
+
+// relayout applies a change to the pane widths, re-rendering every split for its new width
+func (m Model) relayout() (tea.Model, tea.Cmd) {
+	m = m.applyLayout()
+	return m.rerenderAllSplits()
+}
+
+// relayoutAndSave is relayout that also remembers the arrangement for the next run
+func (m Model) relayoutAndSave() (tea.Model, tea.Cmd) {
+	updated, cmd := m.relayout()
+	return updated, tea.Batch(cmd, m.saveStateCmd())
+}
+
+// parentPaneRenderedWidth returns the width of the Miller layout's parent column including its border
+func (m Model) parentPaneRenderedWidth() int {
+	if m.layout.ParentPaneWidth == 0 {
+		return 0
+	}
+	return m.layout.ParentPaneWidth + BorderWidth
+}
+
+// onDivider reports whether a mouse position is on the border between the
+// navigator and the content splits
+func (m Model) onDivider(x, y int) bool {
+	if m.layout.IsFullscreen || m.navigatorCollapsed || len(m.layout.Splits) == 0 {
+		return false
+	}
+	divider := m.layout.Splits[0].X // The content's left border; the navigator's right border is just before it
+	return (x == divider || x == divider-1) && y >= HelpTextHeight
+}
+
+// handleDividerDrag resizes the navigator while the divider is dragged with the
+// left button, reporting whether the event was part of a drag
+func (m Model) handleDividerDrag(msg tea.MouseMsg) (tea.Model, tea.Cmd, bool) {
+	switch {
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft && m.onDivider(msg.X, msg.Y):
+		m.draggingDivider = true
+		return m, nil, true
+	case msg.Action == tea.MouseActionMotion && m.draggingDivider:
+		// Keep the navigator's right border under the pointer
+		target := msg.X + 1 - m.parentPaneRenderedWidth()
+		current := m.layout.LeftPaneWidth + BorderWidth
+		if target == current {
+			return m, nil, true
+		}
+		updated, cmd := m.resizeNavigator(target - current).relayout()
+		return updated, cmd, true
+	case msg.Action == tea.MouseActionRelease && m.draggingDivider:
+		m.draggingDivider = false
+		return m, m.saveStateCmd(), true
+	}
+	return m, nil, false
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// selectedStyle marks selected text in the content pane
+var selectedStyle = lipgloss.NewStyle().Reverse(true)
+
+// cell is a position in a tab's rendered content: a row of the viewport and a
+// screen column within it
+type cell struct {
+	row, col int
+}
+
+// selection is the text selected in a tab, from where it was started to the
+// cursor, both included
+type selection struct {
+	anchor, cursor cell
+	gutter         int  // Columns of line numbers, never selected
+	linewise       bool // Whole rows, whatever the columns
+	dragging       bool // Following a mouse drag
+}
+
+// bounds orders the ends of the selection
+func (s *selection) bounds() (start, end cell) {
+	start, end = s.anchor, s.cursor
+	if end.row < start.row || (end.row == start.row && end.col < start.col) {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// columns is the span of row that is selected, end exclusive, clamped to width
+func (s *selection) columns(row, width int) (int, int, bool) {
+	start, end := s.bounds()
+	if row < start.row || row > end.row {
+		return 0, 0, false
+	}
+	from, to := 0, width
+	if !s.linewise {
+		if row == start.row {
+			from = start.col
+		}
+		if row == end.row {
+			to = min(to, end.col+1)
+		}
+	}
+	return from, to, from < to
+}
+
+// rowSource is where a row of a plain text file comes from: the line, and the
+// byte offset in it that the row's text starts at
+type rowSource struct {
+	line  int
+	start int
+	text  string
+}
+
+// plainFile reports whether the current tab shows a file's own text, laid out
+// by layoutLines, rather than a rendering of it
+func (m Model) plainFile() bool {
+	t := m.tab()
+	if t.mode != FileContent || t.isDir || t.loadErr != nil || t.path == "" {
+		return false
+	}
+	if t.path != StdinPath && t.loadedAs != t.source() {
+		return false
+	}
+	return !m.rendersMarkdown()
+}
+
+// rowSources maps every row of the current plain text file back to its line
+func (m Model) rowSources() ([]string, []rowSource) {
+	content, _ := m.fileSource()
+	lines := strings.Split(content, "\n")
+	textWidth := m.layout.ViewportWidth - gutterWidth(len(lines), m.tab().showLineNumbers)
+
+	var rows []rowSource
+	for i, line := range lines {
+		pos := 0
+		for _, part := range wrapLine(line, textWidth, m.wrapMode) {
+			// Wrapping drops the spaces it breaks at, so find where each part resumes
+			start := pos
+			if offset := strings.Index(line[pos:], part); offset >= 0 {
+				start += offset
+			}
+			rows = append(rows, rowSource{line: i, start: start, text: part})
+			pos = start + len(part)
+		}
+	}
+	return lines, rows
+}
+
+// sourceOffset is the line and byte offset in it of a screen column of a row
+func (m Model) sourceOffset(rows []rowSource, lines []string, c cell) (int, int) {
+	src := rows[max(0, min(c.row, len(rows)-1))]
+	col := c.col - gutterWidth(len(lines), m.tab().showLineNumbers)
+	if m.wrapMode == WrapNone {
+		col += m.tab().xOffset
+	}
+	offset := src.start + len(ansi.Truncate(src.text, max(0, col), ""))
+	return src.line, min(offset, len(lines[src.line]))
+}
+
+// selectedText is the text under the current tab's selection. Plain text files
+// give their original lines, without line numbers or wrapping; anything
+// rendered gives what is shown, without styling.
+func (m Model) selectedText() string {
+	s := m.tab().selection
+	start, end := s.bounds()
+
+	if m.plainFile() {
+		lines, rows := m.rowSources()
+		if len(rows) == 0 {
+			return ""
+		}
+		startLine, startOffset := m.sourceOffset(rows, lines, start)
+		endLine, endOffset := m.sourceOffset(rows, lines, cell{end.row, end.col + 1})
+		if s.linewise {
+			return strings.Join(lines[startLine:endLine+1], "\n")
+		}
+		if startLine == endLine {
+			return lines[startLine][startOffset:max(startOffset, endOffset)]
+		}
+		selected := []string{lines[startLine][startOffset:]}
+		selected = append(selected, lines[startLine+1:endLine]...)
+		return strings.Join(append(selected, lines[endLine][:endOffset]), "\n")
+	}
+
+	rendered := strings.Split(m.tab().content, "\n")
+	var selected []string
+	for row := start.row; row <= min(end.row, len(rendered)-1); row++ {
+		line := ansi.Strip(rendered[row])
+		from, to, _ := s.columns(row, ansi.StringWidth(line))
+		selected = append(selected, strings.TrimRight(ansi.Cut(line, from, to), " "))
+	}
+	return strings.Join(selected, "\n")
+}
+
+// highlightCells shows columns from to to of a rendered line as selected
+func highlightCells(line string, from, to int) string {
+	width := ansi.StringWidth(line)
+	to = min(to, width)
+	if from >= to {
+		return line
+	}
+	return ansi.Truncate(line, from, "") +
+		selectedStyle.Render(ansi.Strip(ansi.Cut(line, from, to))) +
+		ansi.Cut(line, to, width)
+}
+
+// highlightedView renders a tab's viewport with its selection, or the link
+// it has focused, highlighted
+func highlightedView(t *Tab) string {
+	lines := strings.Split(t.content, "\n")
+	if s := t.selection; s != nil {
+		start, end := s.bounds()
+		for row := max(0, start.row); row <= min(end.row, len(lines)-1); row++ {
+			if from, to, ok := s.columns(row, ansi.StringWidth(lines[row])); ok {
+				lines[row] = highlightCells(lines[row], max(from, s.gutter), to)
+			}
+		}
+	}
+	if t.focusedLink > 0 {
+		if l := t.links[t.focusedLink-1]; l.row >= 0 && l.row < len(lines) {
+			lines[l.row] = highlightCells(lines[l.row], l.col, l.col+l.width)
+		}
+	}
+
+	vp := t.viewport
+	vp.SetContent(strings.Join(lines, "\n"))
+	return vp.View()
+}
+
+// newSelection starts a selection in the current tab at a cell
+func (m Model) newSelection(at cell) *selection {
+	s := &selection{anchor: at, cursor: at}
+	if m.plainFile() {
+		content, _ := m.fileSource()
+		s.gutter = gutterWidth(strings.Count(content, "\n")+1, m.tab().showLineNumbers)
+		s.anchor.col = max(s.anchor.col, s.gutter)
+		s.cursor = s.anchor
+	}
+	return s
+}
+
+// startSelection begins selecting from the top of the visible content
+func (m Model) startSelection(linewise bool) Model {
+	m.tab().selection = m.newSelection(cell{row: m.tab().viewport.YOffset})
+	m.tab().selection.linewise = linewise
+	return m
+}
+
+// handleSelectionKey moves the cursor of the selection in the focused tab,
+// reporting whether the key was used
+func (m Model) handleSelectionKey(k keyPress) (tea.Model, tea.Cmd, bool) {
+	t := m.tab()
+	if !m.focusedPane.IsContent() || t.selection == nil {
+		return m, nil, false
+	}
+
+	s := t.selection
+	vp := &t.viewport
+	switch {
+	case key.Matches(k, m.keys.CopySelection):
+		text := m.selectedText()
+		t.selection = nil
+		if lines := strings.Count(text, "\n") + 1; lines == 1 {
+			m.notice = fmt.Sprintf("Copied %d characters", len([]rune(text)))
+		} else {
+			m.notice = fmt.Sprintf("Copied %d lines", lines)
+		}
+		return m, m.copyText(text), true
+	case key.Matches(k, m.keys.PaneSelection):
+		t.selection = nil
+		return m, nil, true
+	case key.Matches(k, m.keys.Visual, m.keys.VisualLine):
+		// Switch between character and line selection, or stop selecting
+		linewise := key.Matches(k, m.keys.VisualLine)
+		if s.linewise == linewise {
+			t.selection = nil
+		} else {
+			s.linewise = linewise
+		}
+		return m, nil, true
+	case key.Matches(k, m.keys.Left):
+		s.cursor.col = max(0, s.cursor.col-1)
+	case key.Matches(k, m.keys.Right):
+		s.cursor.col = min(s.cursor.col+1, max(0, vp.Width-1))
+	case key.Matches(k, m.keys.Up):
+		s.cursor.row--
+	case key.Matches(k, m.keys.Down):
+		s.cursor.row++
+	case key.Matches(k, m.keys.PageUp):
+		s.cursor.row -= vp.Height
+	case key.Matches(k, m.keys.PageDown):
+		s.cursor.row += vp.Height
+	case key.Matches(k, m.keys.HalfPageUp):
+		s.cursor.row -= vp.Height / 2
+	case key.Matches(k, m.keys.HalfPageDown):
+		s.cursor.row += vp.Height / 2
+	case key.Matches(k, m.keys.Top):
+		s.cursor.row = 0
+	case key.Matches(k, m.keys.Bottom):
+		s.cursor.row = vp.TotalLineCount() - 1
+	default:
+		return m, nil, false
+	}
+
+	// Keep the cursor in the content and in view
+	s.cursor.row = max(0, min(s.cursor.row, vp.TotalLineCount()-1))
+	offset := vp.YOffset
+	scrollToRow(vp, s.cursor.row)
+	return m.syncScroll(m.activeSplit, vp.YOffset-offset), nil, true
+}
+
+// scrollToRow scrolls vp as little as possible to show row
+func scrollToRow(vp *viewport.Model, row int) {
+	switch {
+	case row < vp.YOffset:
+		vp.SetYOffset(row)
+	case row >= vp.YOffset+vp.Height:
+		vp.SetYOffset(row - vp.Height + 1)
+	}
+}
+
+// handleSelectionDrag selects text in a split by dragging over it with the
+// left button, reporting whether the event was used
+func (m Model) handleSelectionDrag(msg tea.MouseMsg) (tea.Model, tea.Cmd, bool) {
+	switch {
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		i, ok := m.splitAt(msg.X, msg.Y)
+		if !ok || m.splits[i].tab().mode == HistoryContent || msg.Y < m.splitBodyTop(i) {
+			return m, nil, false
+		}
+		m.mode = NavigatorMode
+		m = m.focusSplit(i)
+		m.tab().selection = m.newSelection(m.cellAt(i, msg.X, msg.Y))
+		m.tab().selection.dragging = true
+		return m, nil, true
+	case msg.Action == tea.MouseActionMotion && m.dragging():
+		m.tab().selection.cursor = m.cellAt(m.activeSplit, msg.X, msg.Y)
+		return m, nil, true
+	case msg.Action == tea.MouseActionRelease && m.dragging():
+		s := m.tab().selection
+		s.dragging = false
+		if s.anchor == s.cursor {
+			m.tab().selection = nil // A click rather than a drag
+		}
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// dragging reports whether a mouse drag is selecting text
+func (m Model) dragging() bool {
+	return m.tab().selection != nil && m.tab().selection.dragging
+}
+
+// cellAt is the content cell under the pointer in split i, clamped to the
+// visible content
+func (m Model) cellAt(i, x, y int) cell {
+	vp := m.splits[i].tab().viewport
+	row := vp.YOffset + max(0, min(y-m.splitBodyTop(i), vp.Height-1))
+	return cell{
+		row: max(0, min(row, vp.TotalLineCount()-1)),
+		col: max(0, min(x-m.splitBodyLeft(i), vp.Width-1)),
+	}
+}
+
+// copyLocation copies the path of the selected entry in the navigator, or the
+// file and top line shown in the content pane as path:line
+func (m Model) copyLocation() (tea.Model, tea.Cmd) {
+	path, line := "", 0
+	if m.focusedPane == NavigatorPane {
+		if item, ok := m.list.SelectedItem().(FileItem); ok {
+			path = item.path
+		}
+	} else if t := m.tab(); t.path != "" && t.path != StdinPath {
+		path, line = t.path, m.topLine()
+		if t.selection != nil {
+			line = m.lineOfRow(t.selection.cursor.row)
+		}
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	// Remote sessions only see paths relative to the served directory
+	if m.rootDir != "" {
+		path = filebrowser.DisplayPath(path, m.rootDir)
+	}
+	if line > 0 {
+		path = fmt.Sprintf("%s:%d", path, line)
+	}
+	m.notice = "Copied " + path
+	return m, m.copyText(path)
+}
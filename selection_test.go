@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notesLine is every line of notes.txt in testTree
+const notesLine = "a line of notes that is long enough to wrap in a narrow pane"
+
+func TestSelectionCopiesOriginalText(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	var clipboard bytes.Buffer
+	m.clipboard = &clipboard
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt, wrapped onto two rows a line
+
+	// Lines come back whole, without numbers or the breaks wrapping added
+	m = send(t, m, keys("V", "j", "j")...)
+	if got, want := m.selectedText(), notesLine+"\n"+notesLine; got != want {
+		t.Errorf("line selection = %q, want %q", got, want)
+	}
+	assertFits(t, m, 80, 20)
+	m = send(t, m, keys("y")...)
+	if m.tab().selection != nil {
+		t.Error("selection kept after copying")
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(notesLine + "\n" + notesLine))
+	if !strings.Contains(clipboard.String(), encoded) {
+		t.Errorf("clipboard sequence %q does not carry the selection", clipboard.String())
+	}
+
+	m = send(t, m, keys("v", "right", "right", "right", "right", "right")...)
+	if got := m.selectedText(); got != "a line" {
+		t.Errorf("character selection = %q, want %q", got, "a line")
+	}
+	m = send(t, m, keys("down")...) // Onto the continuation row
+	if got := m.selectedText(); !strings.HasPrefix(notesLine, got) || len(got) <= len("a line") {
+		t.Errorf("selection across a wrapped row = %q, want the start of the line", got)
+	}
+	m = send(t, m, keys("esc")...)
+	if m.tab().selection != nil || m.mode != NavigatorMode {
+		t.Error("esc did not just cancel the selection")
+	}
+}
+
+func TestSelectionOfRenderedMarkdownIsUnstyled(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "enter")...) // README.md
+	m = send(t, m, keys("V", "G")...)
+	got := m.selectedText()
+	if strings.Contains(got, "\x1b") || !strings.Contains(got, "Some markdown with a link") {
+		t.Errorf("selection = %q, want the text shown without styling", got)
+	}
+}
+
+func TestMouseDragSelects(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt
+	x, y := m.splitBodyLeft(0), m.splitBodyTop(0)
+
+	m = send(t, m,
+		tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft},
+		tea.MouseMsg{X: x + 10, Y: y, Action: tea.MouseActionMotion, Button: tea.MouseButtonLeft},
+		tea.MouseMsg{X: x + 10, Y: y, Action: tea.MouseActionRelease, Button: tea.MouseButtonLeft},
+	)
+	if got := m.selectedText(); got != "a line" {
+		t.Errorf("dragged selection = %q, want %q, the gutter left out", got, "a line")
+	}
+	assertFits(t, m, 80, 20)
+
+	// A click without dragging selects nothing
+	m = send(t, m,
+		tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft},
+		tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionRelease, Button: tea.MouseButtonLeft},
+	)
+	if m.tab().selection != nil {
+		t.Error("a click left a selection")
+	}
+}
+
+func TestCopyLocation(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m.clipboard = &bytes.Buffer{}
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter", ":", "5", "enter", "Y")...)
+	if got := frame(m); !strings.Contains(got, "notes.txt:5") {
+		t.Errorf("path:line not copied:\n%s", got)
+	}
+}
+
+func TestClipboardWrapsForTmuxOfTheSession(t *testing.T) {
+	t.Setenv("TMUX", "") // The server's environment must not decide it
+	for _, environ := range [][]string{{"TERM=xterm"}, {"TERM=xterm", "TMUX=/tmp/tmux-1000/default,1,0"}} {
+		tmux := getenv(environ, "TMUX") != ""
+		var clipboard bytes.Buffer
+		copyToClipboard(&clipboard, tmux, "text")()
+		if wrapped := strings.HasPrefix(clipboard.String(), "\x1bPtmux;"); wrapped != tmux {
+			t.Errorf("environment %v: sequence %q, want wrapped for tmux %v", environ, clipboard.String(), tmux)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// sessionVersion is bumped when the session file changes incompatibly, so
+// older files are ignored rather than misread
+const sessionVersion = 1
+
+// sessionNamePattern is what a session named with -session may contain, so
+// the name is safe to use as a file name
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// session is what the UI showed on quitting, restored on the next launch
+type session struct {
+	Version        int          `json:"version"`
+	Dir            string       `json:"dir"`
+	Selected       string       `json:"selected,omitempty"`
+	History        []string     `json:"history,omitempty"`
+	Tabs           []sessionTab `json:"tabs,omitempty"` // Of the active split
+	ActiveTab      int          `json:"active_tab,omitempty"`
+	ContentFocused bool         `json:"content_focused,omitempty"`
+	Fullscreen     bool         `json:"fullscreen,omitempty"`
+}
+
+// sessionTab is a file open in a tab, with the line at its top
+type sessionTab struct {
+	Path        string `json:"path"`
+	Line        int    `json:"line,omitempty"`
+	LineNumbers bool   `json:"line_numbers"`
+}
+
+// stateDir returns bubbletest's directory under $XDG_STATE_HOME, falling back
+// to ~/.local/state, or "" when neither can be determined
+func stateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "bubbletest")
+}
+
+// sessionPath returns the file of a named session, or of the session started
+// in dir when name is empty, or "" when there is nowhere to keep it
+func sessionPath(name, dir string) string {
+	base := stateDir()
+	if base == "" {
+		return ""
+	}
+	if name != "" {
+		return filepath.Join(base, "sessions", name+".json")
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(base, "sessions", "dirs", hex.EncodeToString(sum[:8])+".json")
+}
+
+// loadSession reads a session file, reporting false when it is missing,
+// unreadable or written by an incompatible version
+func loadSession(path string) (session, bool) {
+	var s session
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, false
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		logger.Warn("ignoring unreadable session file", "path", path, "err", err)
+		return s, false
+	}
+	if s.Version != sessionVersion {
+		logger.Warn("ignoring session file of another version", "path", path, "version", s.Version)
+		return s, false
+	}
+	return s, true
+}
+
+// session captures the navigator and the tabs of the active split
+func (m Model) session() session {
+	s := session{
+		Version:        sessionVersion,
+		Dir:            m.currentDir,
+		History:        m.directoryHistory,
+		ContentFocused: m.focusedPane.IsContent(),
+		Fullscreen:     m.isFullscreen,
+	}
+	if item, ok := m.list.SelectedItem().(FileItem); ok && !m.bookmarkList {
+		s.Selected = item.path
+	}
+
+	// Lines are counted by the active tab at the layout's width, so make each
+	// tab active in turn at the width it was last laid out at
+	split := m.split()
+	active := split.activeTab
+	defer func() { split.activeTab = active }()
+	for i, t := range split.tabs {
+		if t.path == "" || t.path == StdinPath {
+			continue
+		}
+		split.activeTab = i
+		m.layout.ViewportWidth = t.viewport.Width
+		line := m.topLine()
+		if t.pendingLine > 0 {
+			line = t.pendingLine // Never loaded, so still where it was restored to
+		}
+		if i == active {
+			s.ActiveTab = len(s.Tabs)
+		}
+		s.Tabs = append(s.Tabs, sessionTab{Path: t.path, Line: line, LineNumbers: t.showLineNumbers})
+	}
+	return s
+}
+
+// saveSession writes the session to sessionPath, if it is kept
+func (m Model) saveSession() error {
+	if m.sessionPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.session(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.sessionPath, data)
+}
+
+// restoreSession shows what a session showed, leaving out the directories and
+// files that are gone since. The directory is only changed when restoreDir is set.
+func (m Model) restoreSession(s session, restoreDir bool) Model {
+	if restoreDir && isDir(s.Dir) && filebrowser.WithinRoot(s.Dir, m.rootDir) {
+		m.currentDir = s.Dir
+	}
+	m.selectOnListing = s.Selected
+	m = m.listNow()
+
+	m.directoryHistory = []string{}
+	for _, dir := range s.History {
+		if isDir(dir) {
+			m.directoryHistory = append(m.directoryHistory, dir)
+		}
+	}
+
+	// The active tab falls back to the one before it when its file is gone
+	restored, active := 0, 0
+	for i, st := range s.Tabs {
+		if info, err := os.Stat(st.Path); err != nil || info.IsDir() {
+			continue
+		}
+		m = m.openTab(st.Path)
+		m.tab().pendingLine = st.Line
+		m.tab().showLineNumbers = st.LineNumbers
+		if i <= s.ActiveTab {
+			active = m.split().activeTab
+		}
+		restored++
+	}
+	if restored == 0 {
+		return m
+	}
+	m.split().activeTab = active
+	if s.ContentFocused {
+		m = m.focusSplit(m.activeSplit)
+	}
+	m.isFullscreen = s.Fullscreen && s.ContentFocused
+	m.layout = m.CalculateLayout()
+	return m.resizeActiveTab()
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// relaunch starts the UI again in dir, as newTestModel does, with sessions kept
+func relaunch(t *testing.T, dir string) Model {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.StartDir = dir
+	cfg.Theme = "dark"
+	cfg.RestoreSession = true
+	s, err := cfg.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := initialModel(s, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return send(t, m, tea.WindowSizeMsg{Width: 80, Height: 20})
+}
+
+func TestSessionRestoresDirectoryTabsAndScroll(t *testing.T) {
+	project := newTestModel(t, 80, 20).currentDir
+	docs := filepath.Join(project, "docs")
+	notes := filepath.Join(project, "notes.txt")
+
+	m := relaunch(t, project)
+	if m.sessionPath == "" || m.tab().path != "" {
+		t.Fatalf("session path %q, tab %q on the first launch", m.sessionPath, m.tab().path)
+	}
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter", "l", "j", "j", "j", "j")...) // notes.txt
+	line := m.topLine()
+	m = send(t, m, keys("left", "g", "j", "enter", "j", "j", "enter", "f")...) // docs/guide.md
+	if err := m.saveSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = relaunch(t, project)
+	if m.currentDir != docs || len(m.directoryHistory) != 1 || m.directoryHistory[0] != project {
+		t.Errorf("in %s with history %v, want %s after %s", m.currentDir, m.directoryHistory, docs, project)
+	}
+	if m.tab().path != filepath.Join(docs, "guide.md") || !m.isFullscreen || !m.focusedPane.IsContent() {
+		t.Errorf("showing %q, fullscreen %v, focused %v", m.tab().path, m.isFullscreen, m.focusedPane)
+	}
+	assertFits(t, m, 80, 20)
+
+	m = send(t, m, keys("f", "tab")...)
+	if m.tab().path != notes || m.tab().showLineNumbers {
+		t.Fatalf("tab %q with line numbers %v, want %s without", m.tab().path, m.tab().showLineNumbers, notes)
+	}
+	if got := m.topLine(); got != line || line == 1 {
+		t.Errorf("top line = %d, want %d", got, line)
+	}
+}
+
+func TestSessionIgnoresCorruptAndStaleFiles(t *testing.T) {
+	project := newTestModel(t, 80, 20).currentDir
+	path := sessionPath("", project)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := relaunch(t, project)
+	if m.currentDir != project || m.tab().path != "" {
+		t.Errorf("corrupt session restored %s, %q", m.currentDir, m.tab().path)
+	}
+
+	// Paths that are gone are left out, keeping the rest
+	stale := `{"version": 1, "dir": "/gone", "history": ["/gone", "` + project + `"],
+		"tabs": [{"path": "/gone/file.txt"}, {"path": "` + filepath.Join(project, "main.go") + `", "line_numbers": true}], "active_tab": 0}`
+	if err := os.WriteFile(path, []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = relaunch(t, project)
+	if m.currentDir != project || len(m.directoryHistory) != 1 || m.tab().path != filepath.Join(project, "main.go") {
+		t.Errorf("stale session restored %s, history %v, tab %q", m.currentDir, m.directoryHistory, m.tab().path)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99, "dir": "`+project+`"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadSession(path); ok {
+		t.Error("session of another version loaded")
+	}
+}
+
+func TestSessionNames(t *testing.T) {
+	cfg := defaultConfig()
+	for _, name := range []string{"work", "my-project.v2"} {
+		cfg.Session = name
+		if _, err := cfg.resolve(); err != nil {
+			t.Errorf("session %q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"../escape", ".hidden", "a/b"} {
+		cfg.Session = name
+		if _, err := cfg.resolve(); err == nil {
+			t.Errorf("session %q accepted", name)
+		}
+	}
+}
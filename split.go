@@ -0,0 +1,171 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SplitDirection is how the content area is divided between splits. It holds
+// for the whole layout: there is no nesting, so splitting in the other
+// direction re-arranges the existing splits too
+type SplitDirection int
+
+const (
+	SplitVertical   SplitDirection = iota // Splits side by side
+	SplitHorizontal                       // Splits stacked top to bottom
+)
+
+// Split is one content viewport with its own tabs
+type Split struct {
+	tabs      []*Tab // Never empty
+	activeTab int
+}
+
+// SplitLayout holds the calculated dimensions of one split
+type SplitLayout struct {
+	// Screen position of the split's top-left corner, for mouse hit-testing
+	X, Y int
+
+	// Dimensions inside the split's border
+	PaneWidth  int
+	PaneHeight int
+
+	ViewportWidth  int
+	ViewportHeight int
+
+	HasContentHeader bool
+	Visible          bool // Only the active split is visible in fullscreen
+	OutlineWidth     int  // Taken from the content by the heading sidebar, zero without it
+}
+
+// newSplit creates a split holding a single tab
+func newSplit(t *Tab) *Split {
+	return &Split{tabs: []*Tab{t}}
+}
+
+// splitPane returns the Pane value that focuses split i
+func splitPane(i int) Pane {
+	return ContentPane + Pane(i)
+}
+
+// IsContent reports whether the pane is one of the content splits
+func (p Pane) IsContent() bool {
+	return p >= ContentPane
+}
+
+// split returns the active split
+func (m Model) split() *Split {
+	return m.splits[m.activeSplit]
+}
+
+// focusSplit focuses split i and makes it the target for opened files
+func (m Model) focusSplit(i int) Model {
+	m.activeSplit = i
+	m.focusedPane = splitPane(i)
+	m.layout = m.CalculateLayout()
+	return m
+}
+
+// focusPane focuses a pane, tracking the active split when it is a content pane
+func (m Model) focusPane(p Pane) Model {
+	if p.IsContent() {
+		return m.focusSplit(int(p - ContentPane))
+	}
+	m.focusedPane = p
+	return m
+}
+
+// nextPane returns the pane after p, cycling through the navigator and every
+// split, skipping the navigator while it is collapsed
+func (m Model) nextPane(p Pane, step int) Pane {
+	count := len(m.splits) + 1
+	next := Pane((int(p) + step + count) % count)
+	if next == NavigatorPane && m.navigatorCollapsed && len(m.splits) > 1 {
+		return m.nextPane(next, step)
+	}
+	if next == NavigatorPane && m.navigatorCollapsed {
+		return p
+	}
+	return next
+}
+
+// addSplit opens a new split after the active one showing the active tab's
+// file at the same position, so two parts of a file can be read side by side.
+// The direction applies to every split, not only the new one
+func (m Model) addSplit(direction SplitDirection) (tea.Model, tea.Cmd) {
+	current := m.tab()
+	t := newTab(current.showLineNumbers)
+	t.path = current.path
+	t.revision, t.revisionPath = current.revision, current.revisionPath
+	t.viewport.YOffset = current.viewport.YOffset
+	t.data, t.modTime, t.entries, t.loadErr, t.loadedAs = current.data, current.modTime, current.entries, current.loadErr, current.loadedAs
+
+	splits := make([]*Split, 0, len(m.splits)+1)
+	splits = append(splits, m.splits[:m.activeSplit+1]...)
+	splits = append(splits, newSplit(t))
+	m.splits = append(splits, m.splits[m.activeSplit+1:]...)
+	m.splitDirection = direction
+
+	m = m.focusSplit(m.activeSplit + 1)
+	return m.rerenderAllSplits()
+}
+
+// closeSplit closes the active split and its tabs, unless it is the last one
+func (m Model) closeSplit() (tea.Model, tea.Cmd) {
+	if len(m.splits) == 1 {
+		return m, nil
+	}
+
+	for _, t := range m.split().tabs {
+		t.endLoads()
+	}
+	splits := make([]*Split, 0, len(m.splits)-1)
+	splits = append(splits, m.splits[:m.activeSplit]...)
+	m.splits = append(splits, m.splits[m.activeSplit+1:]...)
+	m.scrollLock = m.scrollLock && len(m.splits) > 1
+
+	m = m.focusSplit(min(m.activeSplit, len(m.splits)-1))
+	return m.rerenderAllSplits()
+}
+
+// rerenderAllSplits resizes and re-renders the visible tab of every split,
+// e.g. after the terminal or the split arrangement changed
+func (m Model) rerenderAllSplits() (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for i := range m.splits {
+		updated, cmd := m.rerenderSplit(i)
+		m = updated.(Model)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// rerenderSplit resizes and re-renders the visible tab of split i
+func (m Model) rerenderSplit(i int) (tea.Model, tea.Cmd) {
+	active := m.activeSplit
+	m.activeSplit = i
+	m.layout = m.CalculateLayout()
+	m = m.resizeActiveTab()
+
+	updated, cmd := m.rerenderCurrentFile()
+	m = updated.(Model)
+	m.activeSplit = active
+	m.layout = m.CalculateLayout()
+	return m, cmd
+}
+
+// syncScroll moves every other split by the same amount that split from just
+// scrolled, keeping similar files aligned while comparing them
+func (m Model) syncScroll(from, delta int) Model {
+	if !m.scrollLock || delta == 0 {
+		return m
+	}
+
+	for i, s := range m.splits {
+		if i == from {
+			continue
+		}
+		vp := &s.tab().viewport
+		vp.SetYOffset(vp.YOffset + delta)
+	}
+	return m
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/charmbracelet/wish/activeterm"
+	bm "github.com/charmbracelet/wish/bubbletea"
+	"github.com/muesli/termenv"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sshConfig holds the settings for serving the viewer over SSH
+type sshConfig struct {
+	addr           string
+	root           string
+	hostKeyPath    string
+	authorizedKeys string   // Empty allows any client
+	auditLogPath   string   // Empty logs to stderr
+	settings       settings // Applied to every session
+}
+
+// sessionCounter hands out short ids so audit lines from one session can be grouped
+var sessionCounter atomic.Uint64
+
+// runSSHServer serves a read-only viewer sandboxed to cfg.root until interrupted
+func runSSHServer(cfg sshConfig) error {
+	root := cfg.root
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		root = wd
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("root %q is not a directory", root)
+	}
+
+	var auditOut io.Writer = os.Stderr
+	if cfg.auditLogPath != "" {
+		f, err := os.OpenFile(cfg.auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("opening audit log: %w", err)
+		}
+		defer f.Close()
+		auditOut = f
+	}
+
+	// The server's own stdout is rarely a terminal, so pick colours for the clients instead
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	options := []ssh.Option{
+		wish.WithAddress(cfg.addr),
+		wish.WithHostKeyPath(cfg.hostKeyPath),
+		wish.WithMiddleware(
+			bm.Middleware(sessionHandler(root, auditOut, cfg)),
+			activeterm.Middleware(), // Refuse sessions without a PTY
+		),
+	}
+	if cfg.authorizedKeys != "" {
+		options = append(options, wish.WithAuthorizedKeys(cfg.authorizedKeys))
+	}
+
+	server, err := wish.NewServer(options...)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+
+	log.Printf("Serving %s over SSH on %s", root, cfg.addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+			log.Printf("SSH server stopped: %v", err)
+			done <- nil
+		}
+	}()
+
+	<-done
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// sessionHandler builds a fresh, sandboxed model for every SSH session
+func sessionHandler(root string, auditOut io.Writer, cfg sshConfig) bm.Handler {
+	return func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
+		pty, _, _ := s.Pty()
+
+		id := sessionCounter.Add(1)
+		audit := log.New(auditOut, fmt.Sprintf("[session %d %s@%s] ", id, s.User(), remoteHost(s.RemoteAddr())), log.LstdFlags)
+
+		fingerprint := "none"
+		if key := s.PublicKey(); key != nil {
+			fingerprint = gossh.FingerprintSHA256(key)
+		}
+		audit.Printf("connect key=%s term=%s size=%dx%d", fingerprint, pty.Term, pty.Window.Width, pty.Window.Height)
+
+		start := time.Now()
+		go func() {
+			<-s.Context().Done()
+			audit.Printf("disconnect after %s", time.Since(start).Round(time.Second))
+		}()
+
+		// The client's background cannot be detected from here, so "auto" is dark
+		m := cfg.settings.applyTo(newModel(root, root), true)
+		m.audit = audit
+		m.clipboard = s
+		m.clipboardTmux = getenv(s.Environ(), "TMUX") != ""
+
+		// Size the model from the PTY up front; later changes arrive as WindowSizeMsg
+		sized, _ := m.Update(tea.WindowSizeMsg{Width: pty.Window.Width, Height: pty.Window.Height})
+
+		return sized, []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
+	}
+}
+
+// remoteHost strips the port from a remote address for more readable audit lines
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// getenv looks up name in a session's environment, which holds only what the
+// client chose to send
+func getenv(environ []string, name string) string {
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			return v
+		}
+	}
+	return ""
+}
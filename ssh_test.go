@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// newSandboxedModel creates a test model confined to its project directory as
+// an SSH session is, next to an outside directory that symlinks in the project
+// lead to. Audit lines go to the returned buffer.
+func newSandboxedModel(t *testing.T) (Model, string, *bytes.Buffer) {
+	t.Helper()
+	m := newTestModel(t, 80, 20)
+	project := m.currentDir
+	outside := filepath.Join(filepath.Dir(project), "outside")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(outside, "secret.txt"): "secret\n",
+		filepath.Join(project, "links.md"):   "# Links\n\n[out](../outside/secret.txt)\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"escape": outside, "leak.txt": filepath.Join(outside, "secret.txt")} {
+		if err := os.Symlink(target, filepath.Join(project, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	var audit bytes.Buffer
+	m.audit = log.New(&audit, "", 0)
+	m.rootDir = project
+	m, cmd := m.refreshNavigator()
+	return send(t, m, runCmd(cmd)...), outside, &audit
+}
+
+// selectEntry selects the navigator entry called name
+func selectEntry(t *testing.T, m Model, name string) Model {
+	t.Helper()
+	for i, item := range m.list.Items() {
+		if item.(FileItem).name == name {
+			m.list.Select(i)
+			return m
+		}
+	}
+	t.Fatalf("no entry %s in %s", name, m.currentDir)
+	return m
+}
+
+func TestSandboxRefusesPathsOutsideRoot(t *testing.T) {
+	tests := []struct {
+		name    string
+		audited bool // The refusal is recorded in the audit log
+		run     func(t *testing.T, m Model, outside string) Model
+	}{
+		{"open a symlinked file", true, func(t *testing.T, m Model, outside string) Model {
+			return send(t, selectEntry(t, m, "leak.txt"), keys("enter")...)
+		}},
+		{"enter a symlinked directory", true, func(t *testing.T, m Model, outside string) Model {
+			return send(t, selectEntry(t, m, "escape"), keys("enter")...)
+		}},
+		{"preview in the Miller layout", false, func(t *testing.T, m Model, outside string) Model {
+			m = send(t, m, keys("M")...)
+			return send(t, selectEntry(t, m, "links.md"), keys("up")...) // Onto leak.txt
+		}},
+		{"go above the root", false, func(t *testing.T, m Model, outside string) Model {
+			return send(t, m, keys("M", "left")...)
+		}},
+		{"follow a link", true, func(t *testing.T, m Model, outside string) Model {
+			return send(t, selectEntry(t, m, "links.md"), keys("enter", "}", "enter")...)
+		}},
+		{"jump to a bookmark", true, func(t *testing.T, m Model, outside string) Model {
+			m.places.Bookmarks["o"] = outside
+			return send(t, m, keys("`", "o")...)
+		}},
+		{"jump to a visited directory", false, func(t *testing.T, m Model, outside string) Model {
+			m.places.visit(outside, true, time.Now())
+			return send(t, m, keys("J", "o", "u", "t", "enter")...)
+		}},
+		{"diff against a marked file", false, func(t *testing.T, m Model, outside string) Model {
+			m.markedPath = filepath.Join(outside, "secret.txt")
+			return send(t, selectEntry(t, m, "main.go"), keys("d")...)
+		}},
+		{"restore a session", false, func(t *testing.T, m Model, outside string) Model {
+			return m.restoreSession(session{Version: sessionVersion, Dir: outside}, true)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, outside, audit := newSandboxedModel(t)
+			project := m.currentDir
+			m = tt.run(t, m, outside)
+
+			if m.currentDir != project {
+				t.Errorf("moved to %s", m.currentDir)
+			}
+			for _, s := range m.splits {
+				for _, tab := range s.tabs {
+					if tab.path != "" && !filebrowser.WithinRoot(tab.path, project) || tab.mode == DiffContent {
+						t.Errorf("showing %s in mode %v", tab.path, tab.mode)
+					}
+				}
+			}
+			if tt.audited && !strings.Contains(audit.String(), "denied") {
+				t.Errorf("refusal not audited:\n%s", audit.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/danthegoodman1/bubbletest/filebrowser"
+)
+
+// Tab is a file open in the content pane with its own view state
+type Tab struct {
+	path            string // Empty for the placeholder tab shown when nothing is open
+	content         string // Rendered content set on the viewport
+	viewport        viewport.Model
+	showLineNumbers bool
+	mode            ContentMode
+	revision        string    // Shows the file as of a git revision instead of the working copy
+	revisionPath    string    // Of the file at revision from the repository root, empty for its current name
+	diff            *diffView // The diff shown in DiffContent mode
+	history         list.Model
+	isDir           bool // A directory shown as a listing in the Miller layout's preview
+	preview         bool // Replaced by the next preview instead of staying open
+	xOffset         int  // Columns scrolled sideways while lines are not wrapped
+	pendingLine     int  // Line to scroll to once loaded, zero for none
+	selection       *selection
+
+	// Markdown: the source instead of the rendering, the heading sidebar, and
+	// the headings and links of the document as shown
+	raw           bool
+	outline       *outline
+	headings      []heading
+	links         []docLink
+	focusedLink   int    // One-based index into links, zero while none is focused
+	pendingAnchor string // Heading to scroll to once loaded
+
+	// data is the file as last loaded, or entries the directory, so re-rendering
+	// needs no I/O; loadErr is why the load failed
+	data      []byte
+	modTime   time.Time // Of the file when data was read, zero for revisions
+	entries   []filebrowser.Entry
+	loadErr   error
+	loadedAs  string // source() the data was loaded for, empty before the first load
+	loadingAs string // source() of the load in flight
+	stale     bool   // Reload even if the data is current or on its way, e.g. when reopened
+	load      loadRequest
+	gitLoad   loadRequest // The history or diff git is computing, shown once done
+}
+
+// source identifies what a tab's data is loaded from, changing with the path or revision
+func (t *Tab) source() string {
+	return t.path + "@" + t.revision
+}
+
+// endLoads stops the loads in flight for the tab, which is going away
+func (t *Tab) endLoads() {
+	t.load.end()
+	t.gitLoad.end()
+}
+
+// needsLoad reports whether the tab's data has to be read before rendering
+func (t *Tab) needsLoad() bool {
+	if t.path == "" || t.path == StdinPath {
+		return false
+	}
+	if t.stale {
+		return true
+	}
+	if t.load.active() && t.loadingAs == t.source() {
+		return false // Already on its way
+	}
+	return t.loadedAs != t.source()
+}
+
+// tabHit is the horizontal extent of a tab label in the tab bar, for mouse clicks
+type tabHit struct {
+	start, end int // Columns relative to the start of the tab bar, end exclusive
+	index      int
+}
+
+// newTab creates an empty tab
+func newTab(showLineNumbers bool) *Tab {
+	vp := viewport.New(0, 0)
+	vp.SetContent("Select a file to view its content")
+	return &Tab{viewport: vp, showLineNumbers: showLineNumbers}
+}
+
+// tab returns the active tab of the active split
+func (m Model) tab() *Tab {
+	return m.split().tab()
+}
+
+// tab returns the active tab of a split
+func (s *Split) tab() *Tab {
+	return s.tabs[s.activeTab]
+}
+
+// openTab activates the tab showing path in the active split, opening a new
+// one if needed. The placeholder tab is reused rather than left behind.
+func (m Model) openTab(path string) Model {
+	s := m.split()
+	for i, t := range s.tabs {
+		if t.path == path {
+			t.preview = false // Opening a previewed file keeps it
+			s.activeTab = i
+			m.layout = m.CalculateLayout()
+			return m.resizeActiveTab()
+		}
+	}
+
+	t := newTab(m.showLineNumbers)
+	t.path = path
+	if s.tab().path == "" {
+		s.tabs[s.activeTab] = t
+	} else {
+		s.tabs = append(s.tabs, t)
+		s.activeTab = len(s.tabs) - 1
+	}
+
+	// The header appears with the first open file
+	m.layout = m.CalculateLayout()
+	return m.resizeActiveTab()
+}
+
+// switchTab activates the tab at index in the active split, wrapping around at either end
+func (m Model) switchTab(index int) (tea.Model, tea.Cmd) {
+	s := m.split()
+	s.activeTab = (index + len(s.tabs)) % len(s.tabs)
+	m.layout = m.CalculateLayout()
+	m = m.resizeActiveTab()
+	return m.rerenderCurrentFile()
+}
+
+// closeTab closes the active tab, leaving the placeholder when it was the last
+func (m Model) closeTab() (tea.Model, tea.Cmd) {
+	s := m.split()
+	s.tab().endLoads()
+	if len(s.tabs) == 1 {
+		s.tabs = []*Tab{newTab(m.showLineNumbers)}
+		s.activeTab = 0
+		m.layout = m.CalculateLayout()
+		m = m.resizeActiveTab()
+		return m, nil
+	}
+
+	tabs := make([]*Tab, 0, len(s.tabs)-1)
+	tabs = append(tabs, s.tabs[:s.activeTab]...)
+	s.tabs = append(tabs, s.tabs[s.activeTab+1:]...)
+	return m.switchTab(min(s.activeTab, len(s.tabs)-1))
+}
+
+// resizeActiveTab fits the active tab's viewport to the current layout, since
+// inactive tabs are not resized while hidden
+func (m Model) resizeActiveTab() Model {
+	m.tab().viewport.Width = m.layout.ViewportWidth
+	m.tab().viewport.Height = m.layout.ViewportHeight
+	return m
+}
+
+// tabLabel returns the label of a tab: the full title when active, the file name otherwise
+func (m Model) tabLabel(s *Split, index int) string {
+	if index == s.activeTab {
+		return m.getContentTitle(s.tab())
+	}
+	if s.tabs[index].path == StdinPath {
+		return "stdin"
+	}
+	return filepath.Base(s.tabs[index].path)
+}
+
+// tabBar renders the tab labels of a split as boxes fitting in width, scrolled
+// so the active tab is visible, and returns where each visible label was drawn
+func (m Model) tabBar(s *Split, width int, borderColor lipgloss.Color) (string, []tabHit) {
+	boxStyle := func(active bool) lipgloss.Style {
+		b := lipgloss.RoundedBorder()
+		b.Right = "├"
+		b.Left = "┤"
+		style := lipgloss.NewStyle().BorderStyle(b).Padding(0, 1)
+		if active {
+			return style.BorderForeground(borderColor).Bold(len(s.tabs) > 1)
+		}
+		return style.BorderForeground(m.theme.Muted).Foreground(m.theme.InactiveTab)
+	}
+
+	// Shorten labels that would not fit even alone, leaving room for the box
+	boxes := make([]string, len(s.tabs))
+	for i, t := range s.tabs {
+		label := ansi.Truncate(m.tabLabel(s, i), max(1, width-4), "…")
+		boxes[i] = boxStyle(i == s.activeTab).Italic(t.preview).Render(label)
+	}
+
+	// Find the widest window of tabs that fits and includes the active one
+	start := 0
+	for start < s.activeTab && totalWidth(boxes[start:s.activeTab+1]) > width {
+		start++
+	}
+	end := s.activeTab + 1
+	for end < len(boxes) && totalWidth(boxes[start:end+1]) <= width {
+		end++
+	}
+
+	var hits []tabHit
+	x := 0
+	for i := start; i < end; i++ {
+		w := lipgloss.Width(boxes[i])
+		hits = append(hits, tabHit{start: x, end: x + w, index: i})
+		x += w
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, boxes[start:end]...), hits
+}
+
+// totalWidth sums the rendered widths of boxes
+func totalWidth(boxes []string) int {
+	total := 0
+	for _, box := range boxes {
+		total += lipgloss.Width(box)
+	}
+	return total
+}
+
+// handleTabClick switches tabs on left click and closes them on middle click.
+// Clicking the active tab copies its path. It reports whether a tab was hit.
+func (m Model) handleTabClick(msg tea.MouseMsg) (tea.Model, tea.Cmd, bool) {
+	for i, split := range m.layout.Splits {
+		if !split.HasContentHeader || !split.Visible {
+			continue
+		}
+
+		// The tab bar occupies the three header rows at the top of the split
+		if msg.Y < split.Y || msg.Y >= split.Y+ContentHeaderHeight {
+			continue
+		}
+
+		var barX, barWidth int
+		if m.layout.IsFullscreen {
+			barX, barWidth = split.X+1, split.PaneWidth-1 // After the leading "─"
+		} else {
+			barX, barWidth = split.X+3, split.PaneWidth-2 // After "╭──"
+		}
+
+		_, hits := m.tabBar(m.splits[i], barWidth, "")
+		for _, hit := range hits {
+			if msg.X < barX+hit.start || msg.X >= barX+hit.end {
+				continue
+			}
+			m = m.focusSplit(i)
+			m.mode = NavigatorMode
+			switch msg.Button {
+			case tea.MouseButtonLeft:
+				if hit.index == m.split().activeTab {
+					// Remote sessions only see paths relative to the served directory
+					path := m.tab().path
+					if m.rootDir != "" {
+						path = filebrowser.DisplayPath(path, m.rootDir)
+					}
+					m.notice = "Copied " + path
+					return m, m.copyText(path), true
+				}
+				updated, cmd := m.switchTab(hit.index)
+				return updated, cmd, true
+			case tea.MouseButtonMiddle:
+				m.split().activeTab = hit.index
+				updated, cmd := m.closeTab()
+				return updated, cmd, true
+			}
+			return m, nil, true
+		}
+	}
+
+	return m, nil, false
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	chromastyles "github.com/alecthomas/chroma/styles"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the palette every style in the app is built from
+type Theme struct {
+	// Base is the built-in theme a custom theme starts from; unset colours are inherited
+	Base string `toml:"base,omitempty"`
+
+	// Pane borders
+	FocusedBorder   lipgloss.Color `toml:"focused_border,omitempty"`
+	UnfocusedBorder lipgloss.Color `toml:"unfocused_border,omitempty"`
+	SelectedBorder  lipgloss.Color `toml:"selected_border,omitempty"` // In pane selection mode
+
+	// Lists and tabs
+	Title           lipgloss.Color `toml:"title,omitempty"` // Background of list titles
+	TitleText       lipgloss.Color `toml:"title_text,omitempty"`
+	Accent          lipgloss.Color `toml:"accent,omitempty"` // The selected list item
+	InactiveTab     lipgloss.Color `toml:"inactive_tab,omitempty"`
+	Muted           lipgloss.Color `toml:"muted,omitempty"` // Gutters, ages and descriptions
+	HelpKey         lipgloss.Color `toml:"help_key,omitempty"`
+	HelpKeyText     lipgloss.Color `toml:"help_key_text,omitempty"`
+	DiffAdded       lipgloss.Color `toml:"diff_added,omitempty"`
+	DiffRemoved     lipgloss.Color `toml:"diff_removed,omitempty"`
+	DiffHunk        lipgloss.Color `toml:"diff_hunk,omitempty"`
+	BlameHash       lipgloss.Color `toml:"blame_hash,omitempty"`
+	BlameAuthor     lipgloss.Color `toml:"blame_author,omitempty"`
+	GitUntracked    lipgloss.Color `toml:"git_untracked,omitempty"`
+	GitStaged       lipgloss.Color `toml:"git_staged,omitempty"`
+	GitModified     lipgloss.Color `toml:"git_modified,omitempty"`
+	GitConflicted   lipgloss.Color `toml:"git_conflicted,omitempty"`
+	GitIgnored      lipgloss.Color `toml:"git_ignored,omitempty"`
+	MarkdownStyle   string         `toml:"markdown,omitempty"` // A glamour style: dark, light, dracula, pink, notty or ascii
+	SyntaxHighlight string         `toml:"syntax,omitempty"`   // A chroma style for code blocks, the markdown style's own when empty
+}
+
+// Built-in themes
+var (
+	DarkTheme = Theme{
+		FocusedBorder:   "42",  // Green
+		UnfocusedBorder: "240", // Gray
+		SelectedBorder:  "51",  // Cyan
+		Title:           "62",  // Purple
+		TitleText:       "230", // Cream
+		Accent:          "170", // Pink
+		InactiveTab:     "245", // Light gray
+		Muted:           "240", // Gray
+		HelpKey:         "#ccc",
+		HelpKeyText:     "0",   // Black
+		DiffAdded:       "42",  // Green
+		DiffRemoved:     "196", // Red
+		DiffHunk:        "51",  // Cyan
+		BlameHash:       "214", // Orange
+		BlameAuthor:     "39",  // Blue
+		GitUntracked:    "39",  // Blue
+		GitStaged:       "42",  // Green
+		GitModified:     "214", // Orange
+		GitConflicted:   "196", // Red
+		GitIgnored:      "240", // Gray
+		MarkdownStyle:   "dark",
+	}
+
+	LightTheme = Theme{
+		FocusedBorder:   "28",  // Dark green
+		UnfocusedBorder: "250", // Light gray
+		SelectedBorder:  "31",  // Teal
+		Title:           "62",  // Purple
+		TitleText:       "230", // Cream
+		Accent:          "127", // Magenta
+		InactiveTab:     "243", // Gray
+		Muted:           "245", // Gray
+		HelpKey:         "238", // Dark gray
+		HelpKeyText:     "255", // White
+		DiffAdded:       "28",  // Dark green
+		DiffRemoved:     "160", // Dark red
+		DiffHunk:        "31",  // Teal
+		BlameHash:       "166", // Dark orange
+		BlameAuthor:     "25",  // Dark blue
+		GitUntracked:    "25",  // Dark blue
+		GitStaged:       "28",  // Dark green
+		GitModified:     "166", // Dark orange
+		GitConflicted:   "160", // Dark red
+		GitIgnored:      "248", // Light gray
+		MarkdownStyle:   "light",
+	}
+
+	HighContrastTheme = Theme{
+		FocusedBorder:   "10", // Bright green
+		UnfocusedBorder: "15", // White
+		SelectedBorder:  "14", // Bright cyan
+		Title:           "15", // White
+		TitleText:       "0",  // Black
+		Accent:          "11", // Bright yellow
+		InactiveTab:     "15", // White
+		Muted:           "7",  // Light gray
+		HelpKey:         "15", // White
+		HelpKeyText:     "0",  // Black
+		DiffAdded:       "10", // Bright green
+		DiffRemoved:     "9",  // Bright red
+		DiffHunk:        "14", // Bright cyan
+		BlameHash:       "11", // Bright yellow
+		BlameAuthor:     "12", // Bright blue
+		GitUntracked:    "12", // Bright blue
+		GitStaged:       "10", // Bright green
+		GitModified:     "11", // Bright yellow
+		GitConflicted:   "9",  // Bright red
+		GitIgnored:      "7",  // Light gray
+		MarkdownStyle:   "dark",
+		SyntaxHighlight: "hr_high_contrast",
+	}
+)
+
+// builtinThemes are the themes selectable by name without defining them in the config
+var builtinThemes = map[string]Theme{
+	"dark":          DarkTheme,
+	"light":         LightTheme,
+	"high-contrast": HighContrastTheme,
+}
+
+// resolveTheme looks up a theme by name among the custom and built-in themes,
+// a custom theme replacing the built-in one of the same name. "auto" picks the
+// dark or light theme to suit the terminal background.
+func resolveTheme(name string, custom map[string]Theme, darkBackground bool) (Theme, error) {
+	if name == "auto" {
+		name = "light"
+		if darkBackground {
+			name = "dark"
+		}
+	}
+
+	t, ok := custom[name]
+	if !ok {
+		if t, ok := builtinThemes[name]; ok {
+			return t, nil
+		}
+		return Theme{}, fmt.Errorf("unknown theme %q (want auto, %s or one defined under [themes])", name, strings.Join(builtinThemeNames(), ", "))
+	}
+	base := t.Base
+	if base == "" {
+		base = "dark"
+	}
+	baseTheme, ok := builtinThemes[base]
+	if !ok {
+		return Theme{}, fmt.Errorf("theme %q: unknown base %q (want %s)", name, base, strings.Join(builtinThemeNames(), ", "))
+	}
+	t = inheritTheme(baseTheme, t)
+
+	if _, ok := glamour.DefaultStyles[t.MarkdownStyle]; !ok {
+		return Theme{}, fmt.Errorf("theme %q: unknown markdown style %q", name, t.MarkdownStyle)
+	}
+	if _, ok := chromastyles.Registry[t.SyntaxHighlight]; t.SyntaxHighlight != "" && !ok {
+		return Theme{}, fmt.Errorf("theme %q: unknown syntax style %q", name, t.SyntaxHighlight)
+	}
+	return t, nil
+}
+
+// builtinThemeNames lists the built-in themes for messages
+func builtinThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inheritTheme fills the settings left empty in custom from base
+func inheritTheme(base, custom Theme) Theme {
+	b := reflect.ValueOf(base)
+	c := reflect.ValueOf(&custom).Elem()
+	for i := 0; i < c.NumField(); i++ {
+		if c.Field(i).IsZero() {
+			c.Field(i).Set(b.Field(i))
+		}
+	}
+	return custom
+}
+
+// borderStyle is a pane border in the given colour
+func (t Theme) borderStyle(color lipgloss.Color) lipgloss.Style {
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(color)
+}
+
+// listDelegate is the default list delegate with the selection in the accent colour
+func (t Theme) listDelegate() list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.NormalDesc = d.Styles.NormalDesc.Foreground(t.Muted)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(t.Accent).BorderForeground(t.Accent)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Foreground(t.Accent).BorderForeground(t.Accent)
+	return d
+}
+
+// styleList applies the theme to a list's title and pagination
+func (t Theme) styleList(l *list.Model) {
+	l.Styles.Title = l.Styles.Title.Background(t.Title).Foreground(t.TitleText)
+	l.Styles.ActivePaginationDot = l.Styles.ActivePaginationDot.Foreground(t.Accent)
+	l.Styles.InactivePaginationDot = l.Styles.InactivePaginationDot.Foreground(t.Muted)
+	l.Styles.NoItems = l.Styles.NoItems.Foreground(t.Muted)
+}
+
+// markdownOption returns the glamour style matching the theme
+func (t Theme) markdownOption() glamour.TermRendererOption {
+	style, ok := glamour.DefaultStyles[t.MarkdownStyle]
+	if !ok {
+		return glamour.WithAutoStyle()
+	}
+	config := *style
+	if t.SyntaxHighlight != "" {
+		config.CodeBlock.Chroma = nil
+		config.CodeBlock.Theme = t.SyntaxHighlight
+	}
+	return glamour.WithStyles(config)
+}
+
+// setTheme restyles the model and its lists
+func (m Model) setTheme(t Theme) Model {
+	m.theme = t
+	m.list.SetDelegate(newFileDelegate(t))
+	t.styleList(&m.list)
+	m.parentList.SetDelegate(newFileDelegate(t))
+	t.styleList(&m.parentList)
+	return m
+}
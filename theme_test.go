@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestCustomThemesReplaceBuiltinOnes(t *testing.T) {
+	custom := map[string]Theme{
+		"dark":      {Accent: "#ff0000"},
+		"solarized": {Base: "light", Accent: "#b58900"},
+	}
+	tests := []struct {
+		name           string
+		darkBackground bool
+		accent         string
+	}{
+		{"dark", true, "#ff0000"},
+		{"auto", true, "#ff0000"}, // Auto picks the custom dark theme
+		{"auto", false, string(LightTheme.Accent)},
+		{"light", true, string(LightTheme.Accent)},
+		{"solarized", true, "#b58900"},
+	}
+	for _, tt := range tests {
+		theme, err := resolveTheme(tt.name, custom, tt.darkBackground)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(theme.Accent) != tt.accent {
+			t.Errorf("%s (dark background %v): accent %s, want %s", tt.name, tt.darkBackground, theme.Accent, tt.accent)
+		}
+	}
+
+	// Colours left unset come from the built-in theme replaced
+	if theme, _ := resolveTheme("dark", custom, true); theme.FocusedBorder != DarkTheme.FocusedBorder {
+		t.Errorf("focused border %s, want the built-in dark theme's %s", theme.FocusedBorder, DarkTheme.FocusedBorder)
+	}
+	if _, err := resolveTheme("sepia", custom, true); err == nil {
+		t.Error("unknown theme accepted")
+	}
+
+	cfg := defaultConfig()
+	cfg.Themes = map[string]Theme{"auto": {}}
+	if _, err := cfg.resolve(); err == nil {
+		t.Error("a custom theme named auto was accepted")
+	}
+}
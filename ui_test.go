@@ -0,0 +1,316 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testTree is the directory tree every UI test starts in
+var testTree = map[string]string{
+	"README.md":       "# Project\n\nSome *markdown* with a [link](https://example.com).\n\n- one\n- two\n",
+	"main.go":         "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+	"notes.txt":       strings.Repeat("a line of notes that is long enough to wrap in a narrow pane\n", 40),
+	"docs/guide.md":   "# Guide\n\nRead me.\n",
+	"docs/empty.txt":  "",
+	"src/lib/util.go": "package lib\n",
+}
+
+// newTestModel creates a model in a temporary copy of testTree, isolated from
+// the user's home, config and state, and sized to width x height
+func newTestModel(t *testing.T, width, height int) Model {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home) // Paths show as ~/project
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	dir := filepath.Join(home, "project")
+	for name, content := range testTree {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := defaultConfig()
+	cfg.StartDir = dir
+	cfg.Theme = "dark" // "auto" would query the terminal
+	s, err := cfg.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := initialModel(s, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return send(t, m, tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// send feeds messages to the model in order. Like the program, it runs the
+// commands each message returns and feeds their results back, so loads finish
+// before the next message.
+func send(t *testing.T, m Model, msgs ...tea.Msg) Model {
+	t.Helper()
+	for _, msg := range msgs {
+		updated, cmd := m.Update(msg)
+		m = send(t, updated.(Model), runCmd(cmd)...)
+	}
+	return m
+}
+
+// runCmd runs a command and the batches it returns, leaving out spinner ticks,
+// which would keep coming for as long as something loads
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case nil, spinner.TickMsg, tea.QuitMsg:
+		return nil
+	case tea.BatchMsg:
+		var msgs []tea.Msg
+		for _, c := range msg {
+			msgs = append(msgs, runCmd(c)...)
+		}
+		return msgs
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+// keys turns key names such as "enter", "ctrl+w" or "j" into key messages
+func keys(names ...string) []tea.Msg {
+	special := map[string]tea.KeyType{
+		"enter":  tea.KeyEnter,
+		"esc":    tea.KeyEscape,
+		"up":     tea.KeyUp,
+		"down":   tea.KeyDown,
+		"left":   tea.KeyLeft,
+		"right":  tea.KeyRight,
+		"tab":    tea.KeyTab,
+		"ctrl+w": tea.KeyCtrlW,
+		"ctrl+b": tea.KeyCtrlB,
+		"ctrl+o": tea.KeyCtrlO,
+		"ctrl+t": tea.KeyCtrlT,
+		"f12":    tea.KeyF12,
+	}
+	msgs := make([]tea.Msg, len(names))
+	for i, name := range names {
+		if keyType, ok := special[name]; ok {
+			msgs[i] = tea.KeyMsg{Type: keyType}
+		} else {
+			msgs[i] = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+		}
+	}
+	return msgs
+}
+
+// frame renders the model without colours or trailing spaces
+func frame(m Model) string {
+	lines := strings.Split(ansi.Strip(m.View()), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// assertGolden compares a frame with testdata/<test name>.golden, rewriting it with -update
+func assertGolden(t *testing.T, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("frame differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// assertFits checks that a frame fills the terminal exactly, without lines
+// wider than it, which is where off-by-one layout errors show up
+func assertFits(t *testing.T, m Model, width, height int) {
+	t.Helper()
+	lines := strings.Split(m.View(), "\n")
+	if len(lines) != height {
+		t.Errorf("%dx%d: frame has %d lines, want %d", width, height, len(lines), height)
+	}
+	for i, line := range lines {
+		if w := lipgloss.Width(line); w > width {
+			t.Errorf("%dx%d: line %d is %d columns wide: %q", width, height, i, w, ansi.Strip(line))
+			return
+		}
+	}
+}
+
+func TestGoldenFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"navigator", nil},
+		{"open_file", []string{"j", "j", "j", "j", "enter"}},       // main.go
+		{"markdown", []string{"j", "j", "j", "enter"}},             // README.md
+		{"enter_directory", []string{"j", "enter"}},                // docs
+		{"fullscreen", []string{"j", "j", "j", "j", "enter", "f"}}, // main.go
+		{"pane_selection", []string{"j", "j", "j", "j", "enter", "esc", "left"}},
+		{"shrink_navigator", []string{"<", "<", "<"}},
+		{"collapsed_navigator", []string{"j", "j", "j", "j", "enter", "ctrl+b"}},
+		{"vertical_split", []string{"j", "j", "j", "j", "enter", "|"}},
+		{"miller", []string{"j", "enter", "M"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, 80, 20)
+			m = send(t, m, keys(tt.keys...)...)
+			assertGolden(t, frame(m))
+		})
+	}
+}
+
+func TestGoldenResize(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt
+	m = send(t, m, tea.WindowSizeMsg{Width: 50, Height: 12})
+	assertGolden(t, frame(m))
+}
+
+func TestFramesFitTerminal(t *testing.T) {
+	scenarios := map[string][]string{
+		"navigator":      nil,
+		"file":           {"j", "j", "j", "j", "j", "enter"},
+		"markdown":       {"j", "j", "j", "enter"},
+		"fullscreen":     {"j", "j", "j", "j", "j", "enter", "f"},
+		"pane_selection": {"j", "j", "j", "j", "j", "enter", "esc"},
+		"splits":         {"j", "j", "j", "j", "j", "enter", "|", "|"},
+		"stacked_splits": {"j", "j", "j", "j", "j", "enter", "_"},
+		"miller":         {"j", "enter", "M", "j"},
+		"collapsed":      {"j", "j", "j", "j", "j", "enter", "ctrl+b"},
+	}
+	sizes := [][2]int{{60, 12}, {80, 24}, {81, 25}, {100, 30}, {120, 40}, {157, 43}, {200, 60}}
+
+	for name, script := range scenarios {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s/%dx%d", name, size[0], size[1]), func(t *testing.T) {
+				m := newTestModel(t, size[0], size[1])
+				m = send(t, m, keys(script...)...)
+				assertFits(t, m, size[0], size[1])
+			})
+		}
+	}
+}
+
+func TestFramesFitAfterResize(t *testing.T) {
+	m := newTestModel(t, 120, 40)
+	m = send(t, m, keys("j", "j", "j", "enter", "|")...)
+	for _, size := range [][2]int{{100, 30}, {61, 13}, {200, 50}, {80, 24}} {
+		m = send(t, m, tea.WindowSizeMsg{Width: size[0], Height: size[1]})
+		assertFits(t, m, size[0], size[1])
+	}
+}
+
+// The splits share the space right of the navigator without gaps, leaving
+// PaneSpacing columns free at the edge
+func TestCalculateLayoutSplitsFillPane(t *testing.T) {
+	for _, width := range []int{60, 79, 80, 81, 120, 201} {
+		m := newTestModel(t, width, 30)
+		m = send(t, m, keys("j", "j", "j", "j", "enter", "|", "|")...)
+
+		layout := m.CalculateLayout()
+		last := layout.Splits[len(layout.Splits)-1]
+		right := last.X + last.PaneWidth + BorderWidth
+		if right != width-PaneSpacing {
+			t.Errorf("width %d: splits end at column %d", width, right)
+		}
+		for i := 1; i < len(layout.Splits); i++ {
+			prev := layout.Splits[i-1]
+			if prev.X+prev.PaneWidth+BorderWidth != layout.Splits[i].X {
+				t.Errorf("width %d: split %d starts at %d, want %d", width, i, layout.Splits[i].X, prev.X+prev.PaneWidth+BorderWidth)
+			}
+		}
+	}
+}
+
+// The split keys arrange the whole layout: splitting stacked after side by
+// side stacks every split
+func TestSplitDirectionAppliesToEverySplit(t *testing.T) {
+	m := newTestModel(t, 100, 30)
+	m = send(t, m, keys("j", "j", "j", "j", "enter", "|", "_")...)
+	assertFits(t, m, 100, 30)
+
+	layout := m.CalculateLayout()
+	if len(layout.Splits) != 3 {
+		t.Fatalf("got %d splits, want 3", len(layout.Splits))
+	}
+	for i, split := range layout.Splits[1:] {
+		prev := layout.Splits[i]
+		if split.X != prev.X || split.Y != prev.Y+prev.PaneHeight+BorderWidth {
+			t.Errorf("split %d at (%d, %d) is not stacked below split %d at (%d, %d)", i+1, split.X, split.Y, i, prev.X, prev.Y)
+		}
+	}
+}
+
+func TestLogPanel(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m.logs = newLogBuffer(logPanelSize)
+	m.logs.Write([]byte("level=WARN msg=\"saving state failed\" path=/nowhere\n"))
+
+	m = send(t, m, keys("f12")...)
+	assertFits(t, m, 80, 20)
+	if got := frame(m); !strings.Contains(got, `msg="saving state failed"`) {
+		t.Errorf("log panel does not show the entry:\n%s", got)
+	}
+
+	m = send(t, m, keys("esc")...)
+	if m.showLogs {
+		t.Error("esc did not close the log panel")
+	}
+}
+
+// Left and right walk the tree only in the Miller layout, and page the
+// navigator in the two-pane layout
+func TestLeftAndRightWalkTheTreeInMillerLayout(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	docs := filepath.Join(m.currentDir, "docs")
+	m = send(t, m, keys("j", "enter")...)
+	if m.currentDir != docs {
+		t.Fatalf("current dir = %s, want %s", m.currentDir, docs)
+	}
+	m = send(t, m, keys("left")...)
+	if m.currentDir != docs {
+		t.Errorf("left left %s in the two-pane layout", docs)
+	}
+
+	m = send(t, m, keys("M", "left")...)
+	if m.currentDir == docs {
+		t.Error("left did not go to the parent in the Miller layout")
+	}
+	m = send(t, m, keys("right")...)
+	if m.currentDir != docs {
+		t.Errorf("right opened %s in the Miller layout, want %s", m.currentDir, docs)
+	}
+}
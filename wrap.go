@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// horizontalStep is how many columns the content pane scrolls sideways per key press
+	horizontalStep = 8
+	// continuationMark fills the line number gutter of wrapped continuation lines
+	continuationMark = "↪"
+	// gutterSeparator sits between the line numbers and the text
+	gutterSeparator = " │ "
+)
+
+// WrapMode is how lines wider than the content pane are shown
+type WrapMode int
+
+const (
+	WrapWord WrapMode = iota // Wrapped at word boundaries
+	WrapChar                 // Wrapped at the edge of the pane, mid-word if need be
+	WrapNone                 // Cut off at the edge of the pane and scrolled sideways
+)
+
+// wrapModeNames are the names of the wrap modes in the config and help line
+var wrapModeNames = []string{"word", "char", "none"}
+
+func (w WrapMode) String() string {
+	return wrapModeNames[w]
+}
+
+// next is the mode the toggle_wrap key switches to
+func (w WrapMode) next() WrapMode {
+	return (w + 1) % WrapMode(len(wrapModeNames))
+}
+
+// parseWrapMode maps a wrap mode name from the config to a WrapMode
+func parseWrapMode(name string) (WrapMode, bool) {
+	if name == "" {
+		return WrapWord, true
+	}
+	for i, n := range wrapModeNames {
+		if n == name {
+			return WrapMode(i), true
+		}
+	}
+	return WrapWord, false
+}
+
+// gutterWidth is the width of the line numbers shown before content with
+// lineCount lines, zero without them
+func gutterWidth(lineCount int, numbers bool) int {
+	if !numbers {
+		return 0
+	}
+	return len(strconv.Itoa(lineCount)) + ansi.StringWidth(gutterSeparator)
+}
+
+// layoutLines numbers the lines of content when numbers is set and wraps them
+// to width. Continuation lines get a continuationMark in place of a number, so
+// the numbers keep counting lines of the file.
+func layoutLines(content string, numbers bool, width int, mode WrapMode) string {
+	lines := strings.Split(content, "\n")
+	numberWidth := len(strconv.Itoa(len(lines)))
+	textWidth := width - gutterWidth(len(lines), numbers)
+
+	var result strings.Builder
+	for i, line := range lines {
+		for j, part := range wrapLine(line, textWidth, mode) {
+			if i > 0 || j > 0 {
+				result.WriteString("\n")
+			}
+			if numbers {
+				if j == 0 {
+					fmt.Fprintf(&result, "%*d", numberWidth, i+1)
+				} else {
+					result.WriteString(strings.Repeat(" ", numberWidth-1) + continuationMark)
+				}
+				result.WriteString(gutterSeparator)
+			}
+			result.WriteString(part)
+		}
+	}
+	return result.String()
+}
+
+// lineStarts is the row each line of content starts on once laid out by
+// layoutLines, mapping line numbers of the file to rows of the viewport
+func lineStarts(content string, numbers bool, width int, mode WrapMode) []int {
+	lines := strings.Split(content, "\n")
+	textWidth := width - gutterWidth(len(lines), numbers)
+
+	starts := make([]int, len(lines))
+	row := 0
+	for i, line := range lines {
+		starts[i] = row
+		row += len(wrapLine(line, textWidth, mode))
+	}
+	return starts
+}
+
+// wrapLine splits one line into the rows it takes up at width
+func wrapLine(line string, width int, mode WrapMode) []string {
+	if width <= 0 {
+		return []string{line}
+	}
+	switch mode {
+	case WrapWord:
+		return strings.Split(ansi.Wrap(line, width, ""), "\n")
+	case WrapChar:
+		return strings.Split(ansi.Hardwrap(line, width, true), "\n")
+	}
+	return []string{line}
+}
+
+// scrollSideways cuts the first x columns of text off every line of content,
+// leaving the gutter of line numbers in place
+func scrollSideways(content string, gutter, x int) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lineWidth := ansi.StringWidth(line)
+		lines[i] = ansi.Truncate(line, gutter, "") + ansi.Cut(line, gutter+x, max(lineWidth, gutter+x))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// widestLine is the width of the longest line of content
+func widestLine(content string) int {
+	widest := 0
+	for _, line := range strings.Split(content, "\n") {
+		widest = max(widest, ansi.StringWidth(line))
+	}
+	return widest
+}
+
+// scrollsSideways reports whether the current tab is shown unwrapped, so the
+// left and right keys scroll it
+func (m Model) scrollsSideways() bool {
+	t := m.tab()
+	if m.wrapMode != WrapNone || t.mode != FileContent || t.isDir || t.loadErr != nil || t.path == "" {
+		return false
+	}
+	return !m.rendersMarkdown()
+}
+
+// scrollHorizontally moves the current tab delta columns sideways, within the
+// width of its longest line
+func (m Model) scrollHorizontally(delta int) (tea.Model, tea.Cmd) {
+	m.tab().xOffset = max(0, m.tab().xOffset+delta)
+	return m.rerenderCurrentFile()
+}
+
+// toggleWrap switches every split to the next wrap mode
+func (m Model) toggleWrap() (tea.Model, tea.Cmd) {
+	m.wrapMode = m.wrapMode.next()
+	logger.Debug("wrap mode changed", "wrap", m.wrapMode)
+	return m.rerenderAllSplits()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayoutLines(t *testing.T) {
+	content := "one two three\nshort"
+	tests := []struct {
+		mode WrapMode
+		want string
+	}{
+		{WrapWord, "1 │ one two\n↪ │ three\n2 │ short"},
+		{WrapChar, "1 │ one two t\n↪ │ hree\n2 │ short"},
+		{WrapNone, "1 │ one two three\n2 │ short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			if got := layoutLines(content, true, 13, tt.mode); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if got := layoutLines(content, false, 9, WrapWord); got != "one two\nthree\nshort" {
+		t.Errorf("without line numbers got:\n%s", got)
+	}
+}
+
+func TestHorizontalScroll(t *testing.T) {
+	m := newTestModel(t, 80, 20)
+	m = send(t, m, keys("j", "j", "j", "j", "j", "enter")...) // notes.txt
+	m = send(t, m, keys("W", "W")...)
+	if m.wrapMode != WrapNone {
+		t.Fatalf("wrap mode = %s, want none", m.wrapMode)
+	}
+	if got := frame(m); !strings.Contains(got, " 1 │ a line of notes") || strings.Contains(got, "↪") {
+		t.Errorf("lines wrapped with wrapping off:\n%s", got)
+	}
+
+	m = send(t, m, keys("right")...)
+	got := frame(m)
+	if !strings.Contains(got, " 1 │ "+"a line of notes that is long"[horizontalStep:]) {
+		t.Errorf("not scrolled sideways past the gutter:\n%s", got)
+	}
+	assertFits(t, m, 80, 20)
+
+	// Scrolling stops at the end of the longest line
+	m = send(t, m, keys("right", "right", "right")...)
+	if !strings.Contains(frame(m), "narrow pane│") || m.tab().xOffset >= 3*horizontalStep {
+		t.Errorf("scrolled past the end of the lines:\n%s", frame(m))
+	}
+
+	// Left scrolls back to the start, then returns to the navigator
+	m = send(t, m, keys("left", "left", "left")...)
+	if m.tab().xOffset != 0 || m.focusedPane != NavigatorPane {
+		t.Errorf("xOffset = %d, focused %v; want 0 and the navigator", m.tab().xOffset, m.focusedPane)
+	}
+}